@@ -29,10 +29,14 @@ import (
 )
 
 // NewCloudWatchClient creates a CloudWatch client
-func NewCloudWatchClient() *cloudwatch.CloudWatch {
+func NewCloudWatchClient(opts ...ClientOption) *cloudwatch.CloudWatch {
 	cfg := &aws.Config{Region: aws.String(findAWSRegion(lookupAvailabilityZone))}
 
-	return cloudwatch.New(session.New(cfg))
+	c := cloudwatch.New(session.New(cfg))
+	for _, o := range opts {
+		o(c)
+	}
+	return c
 }
 
 func findAWSRegion(lookupAZ func(ctx context.Context) (io.ReadCloser, error)) string {
@@ -0,0 +1,73 @@
+package awscloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// GzipHandlerName is the name of the request handler compressing PutMetricData bodies
+const GzipHandlerName = "cloudmetrics.GzipRequestHandler"
+
+// ClientOption configures the client created by NewCloudWatchClient
+type ClientOption func(c *cloudwatch.CloudWatch)
+
+// WithGzip compresses the PutMetricData request bodies at the given gzip level, e.g.
+// gzip.DefaultCompression. Invalid levels fall back to gzip.DefaultCompression
+func WithGzip(level int) ClientOption {
+	return func(c *cloudwatch.CloudWatch) {
+		c.Handlers.Build.PushBackNamed(GzipRequestHandler(level))
+	}
+}
+
+// GzipRequestHandler compresses the body of PutMetricData requests once built, so that it can
+// be added to a client created elsewhere:
+//
+//	client.Handlers.Build.PushBackNamed(awscloudmetrics.GzipRequestHandler(gzip.DefaultCompression))
+func GzipRequestHandler(level int) request.NamedHandler {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+
+	return request.NamedHandler{
+		Name: GzipHandlerName,
+		Fn: func(r *request.Request) {
+			if r.Error != nil || r.Operation.Name != "PutMetricData" {
+				return
+			}
+
+			buf := &bytes.Buffer{}
+			zw, err := gzip.NewWriterLevel(buf, level)
+			if err == nil {
+				_, err = io.Copy(zw, r.GetBody())
+			}
+			if err == nil {
+				err = zw.Close()
+			}
+			if err != nil {
+				r.Error = err
+				return
+			}
+
+			r.SetBufferBody(buf.Bytes())
+			r.HTTPRequest.Header.Set("Content-Encoding", "gzip")
+		},
+	}
+}
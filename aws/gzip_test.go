@@ -0,0 +1,70 @@
+package awscloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithGzip(t *testing.T) {
+	var encoding string
+	var body url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		body, err = url.ParseQuery(string(b))
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<PutMetricDataResponse><ResponseMetadata><RequestId>id</RequestId></ResponseMetadata></PutMetricDataResponse>`))
+	}))
+	defer server.Close()
+
+	c := cloudwatch.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})))
+	WithGzip(gzip.BestSpeed)(c)
+
+	_, err := c.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace: aws.String("nmsp"),
+		MetricData: []*cloudwatch.MetricDatum{
+			{MetricName: aws.String("requests"), Value: aws.Float64(1)},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, "PutMetricData", body.Get("Action"))
+	assert.Equal(t, "nmsp", body.Get("Namespace"))
+	assert.Equal(t, "requests", body.Get("MetricData.member.1.MetricName"))
+}
@@ -0,0 +1,156 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// ErrCircuitOpen is returned instead of calling CloudWatch while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of the circuit breaker around the CloudWatch client
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call without calling CloudWatch
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through, which closes the breaker on success
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker wraps a CloudWatch client. It opens after threshold consecutive failures and
+// stops calling CloudWatch for openFor, then lets a probe through to find out whether it is back.
+// Batches rejected as invalid do not count as failures, CloudWatch answered them
+type circuitBreaker struct {
+	client    CloudWatch
+	threshold int
+	openFor   time.Duration
+	clock     Clock
+	onChange  func(from, to BreakerState, failures int)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(client CloudWatch, threshold int, openFor time.Duration, clock Clock, onChange func(from, to BreakerState, failures int)) *circuitBreaker {
+	return &circuitBreaker{
+		client:    client,
+		threshold: threshold,
+		openFor:   openFor,
+		clock:     clock,
+		onChange:  onChange,
+	}
+}
+
+// PutMetricData calls CloudWatch unless the breaker is open
+func (b *circuitBreaker) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	if !b.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	out, err := b.client.PutMetricData(input)
+	b.done(err == nil || isInvalidBatch(err))
+	return out, err
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.openFor {
+			return false
+		}
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// A single probe at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) done(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.transition(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.openedAt = b.clock.Now()
+		b.transition(BreakerOpen)
+	}
+}
+
+// transition must be called with the lock held
+func (b *circuitBreaker) transition(to BreakerState) {
+	from := b.state
+	b.state = to
+	if b.onChange != nil {
+		b.onChange(from, to, b.failures)
+	}
+}
+
+// reportBreaker logs the transitions of the circuit breaker and keeps its state in the self
+// metrics
+func reportBreaker(logger Logger, self *selfMetrics) func(from, to BreakerState, failures int) {
+	return func(from, to BreakerState, failures int) {
+		self.breakerState.Update(int64(to))
+		if to == BreakerOpen {
+			self.breakerOpened.Inc(1)
+			logger.WithField("failures", failures).Warnf("circuit breaker %s -> %s, CloudWatch calls are suspended", from, to)
+			return
+		}
+		logger.Infof("circuit breaker %s -> %s", from, to)
+	}
+}
@@ -0,0 +1,197 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	input := &cloudwatch.PutMetricDataInput{Namespace: aws.String("nmsp")}
+	outage := errors.New("timeout")
+
+	type transition struct{ from, to BreakerState }
+	setup := func() (*circuitBreaker, *mock.CloudWatchMock, *manualClock, *[]transition) {
+		cw := mock.NewCloudWatchMock(mc)
+		clock := newManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+		transitions := &[]transition{}
+		b := newCircuitBreaker(cw, 2, time.Minute, clock, func(from, to BreakerState, _ int) {
+			*transitions = append(*transitions, transition{from, to})
+		})
+		return b, cw, clock, transitions
+	}
+
+	t.Run("OK - Opens after threshold failures", func(t *testing.T) {
+		b, cw, _, transitions := setup()
+		cw.PutMetricDataMock.Return(nil, outage)
+
+		for i := 0; i < 2; i++ {
+			_, err := b.PutMetricData(input)
+			assert.Equal(t, outage, err)
+		}
+		_, err := b.PutMetricData(input)
+		assert.Equal(t, ErrCircuitOpen, err)
+		assert.EqualValues(t, 2, cw.PutMetricDataAfterCounter())
+		assert.Equal(t, []transition{{BreakerClosed, BreakerOpen}}, *transitions)
+	})
+
+	t.Run("OK - Successes reset the failures", func(t *testing.T) {
+		b, cw, _, transitions := setup()
+		for i := 0; i < 3; i++ {
+			cw.PutMetricDataMock.Return(nil, outage)
+			_, _ = b.PutMetricData(input)
+			cw.PutMetricDataMock.Return(nil, nil)
+			_, _ = b.PutMetricData(input)
+		}
+		assert.Empty(t, *transitions)
+	})
+
+	t.Run("OK - Invalid batches are not failures", func(t *testing.T) {
+		b, cw, _, transitions := setup()
+		cw.PutMetricDataMock.Return(nil, awserr.New(cloudwatch.ErrCodeInvalidParameterValueException, "bad", nil))
+		for i := 0; i < 3; i++ {
+			_, _ = b.PutMetricData(input)
+		}
+		assert.Empty(t, *transitions)
+	})
+
+	t.Run("OK - Probe closes the breaker", func(t *testing.T) {
+		b, cw, clock, transitions := setup()
+		cw.PutMetricDataMock.Return(nil, outage)
+		_, _ = b.PutMetricData(input)
+		_, _ = b.PutMetricData(input)
+
+		clock.Add(30 * time.Second)
+		_, err := b.PutMetricData(input)
+		assert.Equal(t, ErrCircuitOpen, err)
+
+		clock.Add(30 * time.Second)
+		cw.PutMetricDataMock.Return(nil, nil)
+		_, err = b.PutMetricData(input)
+		assert.NoError(t, err)
+		assert.Equal(t, []transition{
+			{BreakerClosed, BreakerOpen},
+			{BreakerOpen, BreakerHalfOpen},
+			{BreakerHalfOpen, BreakerClosed},
+		}, *transitions)
+	})
+
+	t.Run("OK - Failed probe opens the breaker again", func(t *testing.T) {
+		b, cw, clock, transitions := setup()
+		cw.PutMetricDataMock.Return(nil, outage)
+		_, _ = b.PutMetricData(input)
+		_, _ = b.PutMetricData(input)
+
+		clock.Add(time.Minute)
+		_, err := b.PutMetricData(input)
+		assert.Equal(t, outage, err)
+		_, err = b.PutMetricData(input)
+		assert.Equal(t, ErrCircuitOpen, err)
+		assert.Equal(t, []transition{
+			{BreakerClosed, BreakerOpen},
+			{BreakerOpen, BreakerHalfOpen},
+			{BreakerHalfOpen, BreakerOpen},
+		}, *transitions)
+	})
+}
+
+func TestPublisher__CircuitBreaker(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	data := []*cloudwatch.MetricDatum{{MetricName: aws.String("a"), Value: aws.Float64(1)}}
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Return(nil, errors.New("timeout"))
+
+	letters := []DeadLetter{}
+	batchErrors := 0
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	selfRegistry := metrics.NewRegistry()
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(cw),
+		WithLogger(logger),
+		WithSelfMetrics(selfRegistry),
+		WithCircuitBreaker(1, time.Minute),
+		WithDeadLetter(DeadLetterFunc(func(l DeadLetter) error {
+			letters = append(letters, l)
+			return nil
+		})),
+		OnBatchError(func(BatchResult) { batchErrors++ }),
+	).(*publisher)
+
+	p.publishMetrics(namespacedData{"nmsp": data})
+	lastAttempt := p.Status().LastAttempt
+	p.publishMetrics(namespacedData{"nmsp": data})
+
+	assert.EqualValues(t, 1, cw.PutMetricDataAfterCounter())
+	require.Len(t, letters, 2)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, ErrCircuitOpen, letters[1].Err)
+	assert.Zero(t, letters[1].Attempts)
+
+	assert.Equal(t, int64(BreakerOpen), selfRegistry.Get(SelfMetricsPrefix+"breaker.state").(metrics.Gauge).Value())
+	assert.Equal(t, int64(1), selfRegistry.Get(SelfMetricsPrefix+"breaker.opened").(metrics.Counter).Count())
+
+	// Denied calls are not accounted for as attempts
+	counter := func(name string) int64 {
+		return selfRegistry.Get(SelfMetricsPrefix + name).(metrics.Counter).Count()
+	}
+	assert.Equal(t, int64(1), counter("breaker.denied"))
+	assert.Equal(t, int64(1), counter("batches.failed"))
+	assert.Equal(t, int64(1), counter("datums.dropped"))
+	assert.EqualValues(t, 1, selfRegistry.Get(SelfMetricsPrefix+"put.latency").(metrics.Timer).Count())
+	assert.Equal(t, 1, batchErrors)
+	assert.Equal(t, 1, p.Status().ConsecutiveFailures)
+	assert.Equal(t, lastAttempt, p.Status().LastAttempt)
+
+	messages := []string{}
+	for _, e := range hook.Entries {
+		messages = append(messages, e.Message)
+	}
+	assert.Contains(t, messages, "circuit breaker closed -> open, CloudWatch calls are suspended")
+}
+
+func TestNew__CircuitBreaker(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	_, err := New(metrics.NewRegistry(), "nmsp", WithCircuitBreaker(3, 0))
+	assert.EqualError(t, err, "circuit breaker requires a positive open duration")
+
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithLogAdapter(NopLogger()),
+		WithCircuitBreaker(3, 0),
+	).(*publisher)
+	_, ok := p.client.(*circuitBreaker)
+	assert.False(t, ok)
+}
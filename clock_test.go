@@ -0,0 +1,154 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+// manualClock is a Clock that only moves forward when told to
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+type clockWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newManualClock(now time.Time) *manualClock {
+	return &manualClock{now: now}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, clockWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Add moves the clock forward by d and fires the waiters that are due
+func (c *manualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = pending
+}
+
+// BlockUntil waits for n pending calls to After
+func (c *manualClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending := len(c.waiters)
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPublisher__SnapshotTimestamp(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	now := time.Date(2020, 1, 1, 12, 0, 3, 0, time.UTC)
+	clock := newManualClock(now)
+
+	timer := metrics.NewTimer()
+	timer.Update(time.Second)
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("requests", metrics.NewCounter()))
+	require.NoError(t, registry.Register("queue", metrics.NewGauge()))
+	require.NoError(t, registry.Register("latency", timer))
+
+	t.Run("OK - Every datum shares the poll timestamp", func(t *testing.T) {
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithClock(clock),
+		).(*publisher)
+
+		data := flatten(p.pollOnce(time.Time{}))
+		require.Len(t, data, 7)
+		for _, d := range data {
+			assert.Equal(t, now, *d.Timestamp, *d.MetricName)
+		}
+	})
+
+	t.Run("OK - Aligned timestamp wins over the clock", func(t *testing.T) {
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithClock(clock),
+		).(*publisher)
+
+		boundary := now.Truncate(time.Minute)
+		for _, d := range flatten(p.pollOnce(boundary)) {
+			assert.Equal(t, boundary, *d.Timestamp, *d.MetricName)
+		}
+	})
+}
+
+func TestPublisher__PollDuration(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	clock := newManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	registry := metrics.NewRegistry()
+	// Polling the registry takes 3 seconds on the clock of the publisher
+	require.NoError(t, registry.Register("slow", metrics.NewFunctionalGauge(func() int64 {
+		clock.Add(3 * time.Second)
+		return 1
+	})))
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithClock(clock),
+	).(*publisher)
+	p.pollOnce(time.Time{})
+
+	require.EqualValues(t, 1, p.self.pollDuration.Count())
+	assert.EqualValues(t, 3*time.Second, p.self.pollDuration.Max())
+}
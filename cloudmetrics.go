@@ -15,13 +15,17 @@ package cloudmetrics
 //	limitations under the License
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/cloudmetrics/health"
 	"github.com/weareyolo/go-metrics"
 )
 
 // Publisher handles the publication of metrics data to CloudWatch
 type Publisher interface {
 	Publish()
+	Status() health.Status
 }
 
 // DatumBuilder handles the datum generation per metric type
@@ -32,6 +36,34 @@ type DatumBuilder interface {
 	BuildMeterData(v metrics.Meter, name string) []*cloudwatch.MetricDatum
 	BuildHistogramData(v metrics.Histogram, name string) []*cloudwatch.MetricDatum
 	BuildTimerData(v metrics.Timer, name string) []*cloudwatch.MetricDatum
+	BuildHealthcheckData(v metrics.Healthcheck, name string) []*cloudwatch.MetricDatum
+	BuildEWMAData(v metrics.EWMA, name string) []*cloudwatch.MetricDatum
+}
+
+// TimestampedDatumBuilder is a DatumBuilder able to stamp datums with a given time, so that
+// every datum of an interval shares the same snapshot timestamp
+type TimestampedDatumBuilder interface {
+	DatumBuilder
+	BuildCounterDataAt(v metrics.Counter, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildGaugeDataAt(v metrics.Gauge, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildGaugeFloat64DataAt(v metrics.GaugeFloat64, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildMeterDataAt(v metrics.Meter, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildHistogramDataAt(v metrics.Histogram, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildTimerDataAt(v metrics.Timer, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildHealthcheckDataAt(v metrics.Healthcheck, name string, t time.Time) []*cloudwatch.MetricDatum
+	BuildEWMADataAt(v metrics.EWMA, name string, t time.Time) []*cloudwatch.MetricDatum
+}
+
+// ValueDatumBuilder is implemented by the DatumBuilders able to build datums from computed
+// values, used for derived metrics
+type ValueDatumBuilder interface {
+	BuildValueDataAt(name string, v float64, unit string, t time.Time) []*cloudwatch.MetricDatum
+}
+
+// Clock is the time source of the publisher
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
 }
 
 // CloudWatch is an interface for *cloudwatch.CloudWatch that clearly identifies the functions
@@ -16,21 +16,57 @@ package cloudmetrics
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/weareyolo/cloudmetrics/datum"
+	"github.com/weareyolo/go-metrics"
 )
 
 type settings struct {
-	Context           context.Context
-	Client            CloudWatch
-	Interval          time.Duration
-	Logger            logrus.FieldLogger
-	Units             map[string]string
-	Dimensions        map[string]string
-	Percentiles       []float64
-	StorageResolution int64
-	DatumBuilder      DatumBuilder
+	Context                context.Context
+	Client                 CloudWatch
+	Interval               time.Duration
+	Logger                 Logger
+	Units                  map[string]string
+	UnitRules              []datum.UnitRule
+	Dimensions             map[string]string
+	Percentiles            []float64
+	StorageResolution      int64
+	ResolutionRules        []datum.ResolutionRule
+	ConversionRules        []datum.ConversionRule
+	Summary                datum.Summary
+	SummaryRules           []datum.SummaryRule
+	DatumBuilder           DatumBuilder
+	Names                  datum.NameTransformer
+	UnitsByTransformedName bool
+	SelfMetrics            metrics.Registry
+	PublishSelfMetrics     bool
+	BatchSentHooks         []func(BatchResult)
+	BatchErrorHooks        []func(BatchResult)
+	IntervalCompleteHooks  []func(IntervalResult)
+	AsyncHooks             int
+	TypeBuilders           []typeBuilder
+	Sources                []Source
+	NamespaceRules         []NamespaceRule
+	AlignInterval          bool
+	StartJitter            time.Duration
+	Clock                  Clock
+	Suppressions           map[MetricType]Suppression
+	ExpireAfter            int
+	UnregisterExpired      bool
+	MetricExpiredHooks     []func(ExpiredMetric)
+	InvalidPolicy          datum.InvalidPolicy
+	Quarantine             time.Duration
+	DeadLetter             DeadLetterHandler
+	BreakerThreshold       int
+	BreakerOpenFor         time.Duration
+	Derived                []Derived
+	ResetSamples           bool
 }
 
 // Option is a type made to override default values for Publisher
@@ -51,15 +87,50 @@ func WithClient(client CloudWatch) Option {
 	}
 }
 
-// WithInterval allows for a custom posting interval; by default, the interval is every 1 minute
+// WithInterval allows for a custom posting interval, which must be positive; by default, the interval
+// is every 1 minute
 func WithInterval(interval time.Duration) Option {
 	return func(s *settings) {
 		s.Interval = interval
 	}
 }
 
-// WithLogger allows to use custom logger
+// WithAlignedInterval flushes on wall clock interval boundaries, e.g. at the start of every
+// minute, and timestamps the datums with the boundary
+func WithAlignedInterval() Option {
+	return func(s *settings) {
+		s.AlignInterval = true
+	}
+}
+
+// WithStartJitter delays the flushes by a random duration up to jitter, picked once at start-up,
+// so that a fleet restarting at once does not publish in bursts. It is capped to the interval
+func WithStartJitter(jitter time.Duration) Option {
+	return func(s *settings) {
+		s.StartJitter = jitter
+	}
+}
+
+// WithClock overrides the time source of the publisher, mostly to make tests deterministic
+func WithClock(clock Clock) Option {
+	return func(s *settings) {
+		s.Clock = clock
+	}
+}
+
+// WithLogger allows to use custom logrus logger, nil uses the default one
 func WithLogger(logger logrus.FieldLogger) Option {
+	return func(s *settings) {
+		if logger == nil {
+			s.Logger = nil
+			return
+		}
+		s.Logger = NewLogrusLogger(logger)
+	}
+}
+
+// WithLogAdapter allows to use any logger implementing Logger, see NewSlogLogger and NopLogger
+func WithLogAdapter(logger Logger) Option {
 	return func(s *settings) {
 		s.Logger = logger
 	}
@@ -74,6 +145,14 @@ func WithUnits(units map[string]string) Option {
 	}
 }
 
+// WithUnitRules specifies the AWS StandardUnits to use for metrics matching the given rules.
+// Exact names given to WithUnits always win, then suffix, prefix and regexp rules
+func WithUnitRules(rules ...datum.UnitRule) Option {
+	return func(s *settings) {
+		s.UnitRules = append(s.UnitRules, rules...)
+	}
+}
+
 // WithDimensions allows for user specified dimensions to be added to the post
 func WithDimensions(dimensions map[string]string) Option {
 	return func(s *settings) {
@@ -88,6 +167,31 @@ func WithPercentiles(percentiles []float64) Option {
 	}
 }
 
+// WithConversionRules declares the unit in which the matching histograms are recorded, e.g.
+// nanoseconds for histograms fed with durations. Their summary is converted to the unit given by
+// WithUnits or WithUnitRules. Timers always record nanoseconds and are not affected by the rules
+func WithConversionRules(rules ...datum.ConversionRule) Option {
+	return func(s *settings) {
+		s.ConversionRules = append(s.ConversionRules, rules...)
+	}
+}
+
+// WithSummary selects the statistics published for histograms and timers, e.g.
+// datum.SummaryCount|datum.SummaryMean|datum.SummaryMax. Defaults to the count and the percentiles
+func WithSummary(summary datum.Summary) Option {
+	return func(s *settings) {
+		s.Summary = summary
+	}
+}
+
+// WithSummaryRules overrides the statistics published for the histograms and timers matching the
+// given rules
+func WithSummaryRules(rules ...datum.SummaryRule) Option {
+	return func(s *settings) {
+		s.SummaryRules = append(s.SummaryRules, rules...)
+	}
+}
+
 // WithStorageResolution specifies the Storage Resolution to use in seconds, default to 60
 func WithStorageResolution(storageResolution int64) Option {
 	return func(s *settings) {
@@ -95,6 +199,15 @@ func WithStorageResolution(storageResolution int64) Option {
 	}
 }
 
+// WithResolutionRules overrides the Storage Resolution of the metrics matching the given rules.
+// Rules with a SampleInterval make the publisher sample those metrics at that cadence and send
+// every sample on flush
+func WithResolutionRules(rules ...datum.ResolutionRule) Option {
+	return func(s *settings) {
+		s.ResolutionRules = append(s.ResolutionRules, rules...)
+	}
+}
+
 // WithBuilder specifies the DatumBuilder to use
 func WithBuilder(b DatumBuilder) Option {
 	return func(s *settings) {
@@ -102,6 +215,212 @@ func WithBuilder(b DatumBuilder) Option {
 	}
 }
 
+// WithNamePrefix prepends prefix to every metric name
+func WithNamePrefix(prefix string) Option {
+	return func(s *settings) {
+		s.Names.Prefix = prefix
+	}
+}
+
+// WithNameSuffix appends suffix to every metric name
+func WithNameSuffix(suffix string) Option {
+	return func(s *settings) {
+		s.Names.Suffix = suffix
+	}
+}
+
+// WithNameSanitizer replaces the characters of metric names matched by pattern with replacement.
+// A nil pattern defaults to datum.DefaultSanitizer
+func WithNameSanitizer(pattern *regexp.Regexp, replacement string) Option {
+	return func(s *settings) {
+		if pattern == nil {
+			pattern = datum.DefaultSanitizer
+		}
+		s.Names.Sanitizer = pattern
+		s.Names.SanitizeReplacement = replacement
+	}
+}
+
+// WithNameCase converts metric names to the given case
+func WithNameCase(c datum.NameCase) Option {
+	return func(s *settings) {
+		s.Names.Case = c
+	}
+}
+
+// WithNameRewrite adds a regexp rewrite rule applied to metric names; rules run in the order
+// they are given
+func WithNameRewrite(pattern *regexp.Regexp, replacement string) Option {
+	return func(s *settings) {
+		s.Names.Rules = append(s.Names.Rules, datum.RewriteRule{
+			Pattern:     pattern,
+			Replacement: replacement,
+		})
+	}
+}
+
+// WithTransformedUnitLookup makes WithUnits keys match the transformed metric names instead of
+// the original registry names
+func WithTransformedUnitLookup() Option {
+	return func(s *settings) {
+		s.UnitsByTransformedName = true
+	}
+}
+
+// WithSelfMetrics registers the metrics the publisher keeps about itself in the given registry,
+// under the SelfMetricsPrefix. By default they are kept in a private registry
+func WithSelfMetrics(registry metrics.Registry) Option {
+	return func(s *settings) {
+		s.SelfMetrics = registry
+	}
+}
+
+// WithSelfMetricsPublished publishes the metrics the publisher keeps about itself along with the
+// application metrics
+func WithSelfMetricsPublished() Option {
+	return func(s *settings) {
+		s.PublishSelfMetrics = true
+	}
+}
+
+// OnBatchSent registers a callback invoked after each successful PutMetricData call
+func OnBatchSent(f func(BatchResult)) Option {
+	return func(s *settings) {
+		s.BatchSentHooks = append(s.BatchSentHooks, f)
+	}
+}
+
+// OnBatchError registers a callback invoked after each failed PutMetricData call
+func OnBatchError(f func(BatchResult)) Option {
+	return func(s *settings) {
+		s.BatchErrorHooks = append(s.BatchErrorHooks, f)
+	}
+}
+
+// OnIntervalComplete registers a callback invoked once all the batches of an interval were sent
+func OnIntervalComplete(f func(IntervalResult)) Option {
+	return func(s *settings) {
+		s.IntervalCompleteHooks = append(s.IntervalCompleteHooks, f)
+	}
+}
+
+// OnMetricExpired registers a callback invoked when a metric expires, see WithExpiry
+func OnMetricExpired(f func(ExpiredMetric)) Option {
+	return func(s *settings) {
+		s.MetricExpiredHooks = append(s.MetricExpiredHooks, f)
+	}
+}
+
+// WithAsyncHooks runs the callbacks in a dedicated goroutine, living as long as Publish, instead
+// of the publishing loop. Up to bufferSize events wait to be handled, further events are dropped
+func WithAsyncHooks(bufferSize int) Option {
+	return func(s *settings) {
+		s.AsyncHooks = bufferSize
+	}
+}
+
+// WithTypeBuilder registers the function generating the datums of metrics of type t, which is
+// checked before the built-in types. t is either a concrete type, e.g.
+// reflect.TypeOf((*Sketch)(nil)), or an interface, e.g. reflect.TypeOf((*Quantiler)(nil)).Elem()
+func WithTypeBuilder(t reflect.Type, f BuildFunc) Option {
+	return func(s *settings) {
+		s.TypeBuilders = append(s.TypeBuilders, typeBuilder{typ: t, build: f})
+	}
+}
+
+// WithSources publishes additional registries, each to its own namespace and with its own name
+// prefix and dimensions. All registries are polled on the same tick and batched per namespace
+func WithSources(sources ...Source) Option {
+	return func(s *settings) {
+		s.Sources = append(s.Sources, sources...)
+	}
+}
+
+// WithNamespaceRules publishes the metrics matching the rules to the namespace of the rule
+// instead of the namespace of their registry
+func WithNamespaceRules(rules ...NamespaceRule) Option {
+	return func(s *settings) {
+		s.NamespaceRules = append(s.NamespaceRules, rules...)
+	}
+}
+
+// WithSuppression skips the datums of a metric type according to policy, e.g. idle counters
+// that would otherwise be published as flat lines every interval
+func WithSuppression(typ MetricType, policy Suppression) Option {
+	return func(s *settings) {
+		if s.Suppressions == nil {
+			s.Suppressions = map[MetricType]Suppression{}
+		}
+		s.Suppressions[typ] = policy
+	}
+}
+
+// WithExpiry stops publishing the metrics that did not change for idleIntervals intervals, such
+// as the metrics of short-lived entities. They are published again as soon as they change,
+// unless unregister is set, in which case they are removed from their registry
+func WithExpiry(idleIntervals int, unregister bool) Option {
+	return func(s *settings) {
+		s.ExpireAfter = idleIntervals
+		s.UnregisterExpired = unregister
+	}
+}
+
+// WithInvalidDatumPolicy decides what happens to the datums CloudWatch would reject, such as NaN
+// values or empty dimension values. They are dropped by default
+func WithInvalidDatumPolicy(policy datum.InvalidPolicy) Option {
+	return func(s *settings) {
+		s.InvalidPolicy = policy
+	}
+}
+
+// WithQuarantine sets how long metrics rejected by CloudWatch are not sent again, 10 minutes by
+// default. 0 disables the quarantine, the rejected datums are still isolated from the others
+// of their batch
+func WithQuarantine(cooldown time.Duration) Option {
+	return func(s *settings) {
+		s.Quarantine = cooldown
+	}
+}
+
+// WithDeadLetter hands the datums that could not be delivered to h, see OpenDeadLetterFile and
+// NewLogDeadLetter
+func WithDeadLetter(h DeadLetterHandler) Option {
+	return func(s *settings) {
+		s.DeadLetter = h
+	}
+}
+
+// WithCircuitBreaker stops calling CloudWatch for openFor after threshold consecutive failed
+// calls, e.g. during an outage, instead of waiting for every batch to time out. The datums are
+// handed to the dead-letter handler meanwhile. A single probe call is then let through, and
+// calls resume when it succeeds
+func WithCircuitBreaker(threshold int, openFor time.Duration) Option {
+	return func(s *settings) {
+		s.BreakerThreshold = threshold
+		s.BreakerOpenFor = openFor
+	}
+}
+
+// WithDerived publishes metrics computed at poll time from the other metrics of each registry,
+// e.g. an error rate:
+//
+//	Derived{Name: "errors.rate", Unit: cloudwatch.StandardUnitPercent,
+//		Expr: Scale(Ratio(Metric("errors"), Metric("requests")), 100)}
+func WithDerived(derived ...Derived) Option {
+	return func(s *settings) {
+		s.Derived = append(s.Derived, derived...)
+	}
+}
+
+// WithIntervalReset clears the samples of histograms and timers once their data is built on
+// flush, so that every datum describes the observations of its interval only. Timers must
+// implement Clear(), as ResettableTimer does, go-metrics timers are left untouched
+func WithIntervalReset() Option {
+	return func(s *settings) {
+		s.ResetSamples = true
+	}
+}
+
 func getSettings(opts []Option) *settings {
 	s := &settings{
 		Context:           context.Background(),
@@ -110,6 +429,7 @@ func getSettings(opts []Option) *settings {
 		Dimensions:        map[string]string{},
 		Percentiles:       []float64{.5, .75, .95, .99},
 		StorageResolution: 60,
+		Quarantine:        10 * time.Minute,
 	}
 
 	for _, o := range opts {
@@ -119,10 +439,177 @@ func getSettings(opts []Option) *settings {
 	return s
 }
 
-func newLogger() logrus.FieldLogger {
+func (s *settings) validate() error {
+	if s.Interval <= 0 {
+		return fmt.Errorf("invalid interval %v, must be positive", s.Interval)
+	}
+	for _, src := range s.Sources {
+		if src.Registry == nil || src.Namespace == "" {
+			return errors.New("sources require a registry and a namespace")
+		}
+	}
+	for _, r := range s.NamespaceRules {
+		if r.Namespace == "" {
+			return fmt.Errorf("empty namespace for %s", r.Describe())
+		}
+	}
+	for _, r := range s.Names.Rules {
+		if r.Pattern == nil {
+			return fmt.Errorf("name rewrite to %q requires a pattern", r.Replacement)
+		}
+	}
+	for _, b := range s.TypeBuilders {
+		if b.typ == nil || b.build == nil {
+			return errors.New("type builders require a type and a function")
+		}
+	}
+	for _, d := range s.Derived {
+		if d.Name == "" || d.Expr == nil {
+			return errors.New("derived metrics require a name and an expression")
+		}
+		if d.Unit != "" && !datum.IsValidUnit(d.Unit) {
+			return fmt.Errorf("invalid unit %s for derived metric %s", d.Unit, d.Name)
+		}
+	}
+	if s.BreakerThreshold > 0 && s.BreakerOpenFor <= 0 {
+		return errors.New("circuit breaker requires a positive open duration")
+	}
+	if err := datum.ValidateUnits(s.Units, s.UnitRules); err != nil {
+		return err
+	}
+	if err := datum.ValidateConversions(s.ConversionRules); err != nil {
+		return err
+	}
+	if s.Summary != 0 {
+		if err := datum.ValidateSummary(s.Summary); err != nil {
+			return err
+		}
+	}
+	if err := datum.ValidateSummaries(s.SummaryRules); err != nil {
+		return err
+	}
+	return datum.ValidateResolutions(s.ResolutionRules)
+}
+
+// dropInvalid removes the invalid values reported by validate so they fall back to defaults
+func (s *settings) dropInvalid() {
+	if s.Interval <= 0 {
+		s.Interval = time.Minute
+	}
+
+	for name, unit := range s.Units {
+		if !datum.IsValidUnit(unit) {
+			delete(s.Units, name)
+		}
+	}
+
+	rules := s.UnitRules[:0]
+	for _, r := range s.UnitRules {
+		if datum.IsValidUnit(r.Unit) {
+			rules = append(rules, r)
+		}
+	}
+	s.UnitRules = rules
+
+	conversions := s.ConversionRules[:0]
+	for _, r := range s.ConversionRules {
+		if datum.ValidateConversions([]datum.ConversionRule{r}) == nil {
+			conversions = append(conversions, r)
+		}
+	}
+	s.ConversionRules = conversions
+
+	if datum.ValidateSummary(s.Summary) != nil {
+		s.Summary = 0
+	}
+	summaries := s.SummaryRules[:0]
+	for _, r := range s.SummaryRules {
+		if datum.ValidateSummary(r.Summary) == nil {
+			summaries = append(summaries, r)
+		}
+	}
+	s.SummaryRules = summaries
+
+	resolutions := s.ResolutionRules[:0]
+	for _, r := range s.ResolutionRules {
+		if datum.ValidateResolutions([]datum.ResolutionRule{r}) == nil {
+			resolutions = append(resolutions, r)
+		}
+	}
+	s.ResolutionRules = resolutions
+
+	builders := s.TypeBuilders[:0]
+	for _, b := range s.TypeBuilders {
+		if b.typ != nil && b.build != nil {
+			builders = append(builders, b)
+		}
+	}
+	s.TypeBuilders = builders
+
+	sources := s.Sources[:0]
+	for _, src := range s.Sources {
+		if src.Registry != nil && src.Namespace != "" {
+			sources = append(sources, src)
+		}
+	}
+	s.Sources = sources
+
+	rewrites := s.Names.Rules[:0]
+	for _, r := range s.Names.Rules {
+		if r.Pattern != nil {
+			rewrites = append(rewrites, r)
+		}
+	}
+	s.Names.Rules = rewrites
+
+	routes := s.NamespaceRules[:0]
+	for _, r := range s.NamespaceRules {
+		if r.Namespace != "" {
+			routes = append(routes, r)
+		}
+	}
+	s.NamespaceRules = routes
+
+	derived := s.Derived[:0]
+	for _, d := range s.Derived {
+		if d.Name != "" && d.Expr != nil && (d.Unit == "" || datum.IsValidUnit(d.Unit)) {
+			derived = append(derived, d)
+		}
+	}
+	s.Derived = derived
+
+	if s.BreakerOpenFor <= 0 {
+		s.BreakerThreshold = 0
+	}
+}
+
+func (s *settings) builderOptions() []datum.Option {
+	opts := []datum.Option{datum.WithNameTransformer(&s.Names)}
+	if len(s.UnitRules) > 0 {
+		opts = append(opts, datum.WithUnitRules(s.UnitRules...))
+	}
+	if len(s.ResolutionRules) > 0 {
+		opts = append(opts, datum.WithResolutionRules(s.ResolutionRules...))
+	}
+	if len(s.ConversionRules) > 0 {
+		opts = append(opts, datum.WithConversionRules(s.ConversionRules...))
+	}
+	if s.Summary != 0 {
+		opts = append(opts, datum.WithSummary(s.Summary))
+	}
+	if len(s.SummaryRules) > 0 {
+		opts = append(opts, datum.WithSummaryRules(s.SummaryRules...))
+	}
+	if s.UnitsByTransformedName {
+		opts = append(opts, datum.WithTransformedUnitLookup())
+	}
+	return opts
+}
+
+func newLogger() Logger {
+	// Callers are not reported: logrus would report the Logger adapter rather than the call site
 	l := logrus.New()
-	l.SetReportCaller(true)
 	l.SetFormatter(new(logrus.JSONFormatter))
 	l.SetLevel(logrus.ErrorLevel)
-	return l
+	return NewLogrusLogger(l)
 }
@@ -16,6 +16,8 @@ package cloudmetrics
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/datum"
 	"github.com/weareyolo/cloudmetrics/mock"
 )
 
@@ -39,6 +42,7 @@ func TestGetSettings(t *testing.T) {
 			Dimensions:        map[string]string{},
 			Percentiles:       []float64{.5, .75, .95, .99},
 			StorageResolution: 60,
+			Quarantine:        10 * time.Minute,
 		}, s)
 	})
 
@@ -61,6 +65,7 @@ func TestGetSettings(t *testing.T) {
 			Percentiles:       []float64{.5, .75, .95, .99},
 			DatumBuilder:      b,
 			StorageResolution: 60,
+			Quarantine:        10 * time.Minute,
 		}, s)
 	})
 
@@ -89,6 +94,7 @@ func TestGetSettings(t *testing.T) {
 			WithDimensions(dimensions),
 			WithPercentiles(percentiles),
 			WithStorageResolution(30),
+			WithQuarantine(time.Minute),
 		})
 		require.NotNil(t, s)
 
@@ -96,11 +102,114 @@ func TestGetSettings(t *testing.T) {
 			Context:           ctx,
 			Client:            cw,
 			Interval:          6 * time.Millisecond,
-			Logger:            logger,
+			Logger:            NewLogrusLogger(logger),
 			Units:             units,
 			Dimensions:        dimensions,
 			Percentiles:       percentiles,
 			StorageResolution: 30,
+			Quarantine:        time.Minute,
 		}, s)
 	})
+
+	t.Run("OK - With name transformation", func(t *testing.T) {
+		rewrite := regexp.MustCompile(`/`)
+
+		s := getSettings([]Option{
+			WithNamePrefix("app."),
+			WithNameSuffix(".v1"),
+			WithNameSanitizer(nil, "_"),
+			WithNameCase(datum.CaseLower),
+			WithNameRewrite(rewrite, "."),
+			WithTransformedUnitLookup(),
+		})
+		require.NotNil(t, s)
+
+		assert.Equal(t, datum.NameTransformer{
+			Prefix:              "app.",
+			Suffix:              ".v1",
+			Rules:               []datum.RewriteRule{{Pattern: rewrite, Replacement: "."}},
+			Sanitizer:           datum.DefaultSanitizer,
+			SanitizeReplacement: "_",
+			Case:                datum.CaseLower,
+		}, s.Names)
+		assert.True(t, s.UnitsByTransformedName)
+		assert.Len(t, s.builderOptions(), 2)
+	})
+
+	t.Run("NOK - Non-positive interval", func(t *testing.T) {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			s := getSettings([]Option{WithInterval(interval)})
+			require.NotNil(t, s)
+			assert.EqualError(t, s.validate(), fmt.Sprintf("invalid interval %v, must be positive", interval))
+
+			s.dropInvalid()
+			assert.Equal(t, time.Minute, s.Interval)
+			assert.NoError(t, s.validate())
+		}
+	})
+
+	t.Run("NOK - Name rewrite without pattern", func(t *testing.T) {
+		s := getSettings([]Option{
+			WithNameRewrite(nil, "."),
+			WithNameRewrite(regexp.MustCompile(`/`), "."),
+		})
+		require.NotNil(t, s)
+		assert.EqualError(t, s.validate(), `name rewrite to "." requires a pattern`)
+
+		s.dropInvalid()
+		require.Len(t, s.Names.Rules, 1)
+		assert.NotNil(t, s.Names.Rules[0].Pattern)
+		assert.NoError(t, s.validate())
+	})
+
+	t.Run("OK - With unit rules", func(t *testing.T) {
+		s := getSettings([]Option{
+			WithUnitRules(datum.SuffixUnit(".bytes", cloudwatch.StandardUnitBytes)),
+			WithUnitRules(datum.PrefixUnit("lat.", "Secs")),
+		})
+		require.NotNil(t, s)
+		require.Len(t, s.UnitRules, 2)
+		assert.Error(t, s.validate())
+
+		s.dropInvalid()
+		require.Len(t, s.UnitRules, 1)
+		assert.Equal(t, cloudwatch.StandardUnitBytes, s.UnitRules[0].Unit)
+		assert.NoError(t, s.validate())
+	})
+
+	t.Run("OK - With conversion rules", func(t *testing.T) {
+		s := getSettings([]Option{
+			WithConversionRules(
+				datum.SuffixConversion(".ns", datum.UnitNanoseconds),
+				datum.PrefixConversion("size.", "bytes"),
+			),
+		})
+		require.NotNil(t, s)
+		assert.Error(t, s.validate())
+
+		s.dropInvalid()
+		require.Len(t, s.ConversionRules, 1)
+		assert.Equal(t, datum.UnitNanoseconds, s.ConversionRules[0].From)
+		assert.NoError(t, s.validate())
+		assert.Len(t, s.builderOptions(), 2)
+	})
+
+	t.Run("OK - With summaries", func(t *testing.T) {
+		s := getSettings([]Option{
+			WithSummary(datum.SummaryCount | 1<<10),
+			WithSummaryRules(
+				datum.SuffixSummary(".latency", datum.SummaryMean|datum.SummaryMax),
+				datum.PrefixSummary("db.", 0),
+			),
+		})
+		require.NotNil(t, s)
+		assert.Error(t, s.validate())
+
+		s.dropInvalid()
+		assert.Zero(t, s.Summary)
+		require.Len(t, s.SummaryRules, 1)
+		assert.Equal(t, datum.SummaryMean|datum.SummaryMax, s.SummaryRules[0].Summary)
+		assert.NoError(t, s.validate())
+		assert.Len(t, s.builderOptions(), 2)
+	})
 }
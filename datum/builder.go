@@ -23,25 +23,81 @@ import (
 	"github.com/weareyolo/go-metrics"
 )
 
-var convertDuration = map[string](func(v float64) float64){
-	cloudwatch.StandardUnitSeconds:      func(v float64) float64 { return v / float64(time.Second) },
-	cloudwatch.StandardUnitMilliseconds: func(v float64) float64 { return v / float64(time.Millisecond) },
-	cloudwatch.StandardUnitMicroseconds: func(v float64) float64 { return v / float64(time.Microsecond) },
+// Builder handles the datum generation
+type Builder struct {
+	units                  map[string]string
+	dimensions             []*cloudwatch.Dimension
+	percentiles            []float64
+	storageResolution      *int64
+	names                  *NameTransformer
+	unitsByTransformedName bool
+	unitRules              []UnitRule
+	resolutionRules        []ResolutionRule
+	conversionRules        []ConversionRule
+	summary                Summary
+	summaryRules           []SummaryRule
 }
 
-func identity(v float64) float64 { return v }
+// Option is a type made to override default values for Builder
+type Option func(b *Builder)
 
-// Builder handles the datum generation
-type Builder struct {
-	units             map[string]string
-	dimensions        []*cloudwatch.Dimension
-	percentiles       []float64
-	storageResolution *int64
+// WithNameTransformer applies the given transformation to every metric name
+func WithNameTransformer(t *NameTransformer) Option {
+	return func(b *Builder) {
+		b.names = t
+	}
+}
+
+// WithTransformedUnitLookup makes unit lookups use the transformed metric name instead of
+// the original one
+func WithTransformedUnitLookup() Option {
+	return func(b *Builder) {
+		b.unitsByTransformedName = true
+	}
+}
+
+// WithUnitRules assigns units to the metrics not listed in the units map
+func WithUnitRules(rules ...UnitRule) Option {
+	return func(b *Builder) {
+		b.unitRules = append(b.unitRules, rules...)
+		SortByPriority(b.unitRules, func(i int) Matcher { return b.unitRules[i].Matcher })
+	}
+}
+
+// WithResolutionRules overrides the storage resolution of the metrics matching the rules
+func WithResolutionRules(rules ...ResolutionRule) Option {
+	return func(b *Builder) {
+		b.resolutionRules = SortResolutionRules(append(b.resolutionRules, rules...))
+	}
+}
+
+// WithConversionRules declares the unit in which the matching histograms are recorded, to convert
+// their summary to the unit of the metric. Timers always record nanoseconds
+func WithConversionRules(rules ...ConversionRule) Option {
+	return func(b *Builder) {
+		b.conversionRules = append(b.conversionRules, rules...)
+		SortByPriority(b.conversionRules, func(i int) Matcher { return b.conversionRules[i].Matcher })
+	}
+}
+
+// WithSummary selects the statistics published for histograms and timers, DefaultSummary by default
+func WithSummary(summary Summary) Option {
+	return func(b *Builder) {
+		b.summary = summary
+	}
+}
+
+// WithSummaryRules overrides the statistics published for the matching histograms and timers
+func WithSummaryRules(rules ...SummaryRule) Option {
+	return func(b *Builder) {
+		b.summaryRules = append(b.summaryRules, rules...)
+		SortByPriority(b.summaryRules, func(i int) Matcher { return b.summaryRules[i].Matcher })
+	}
 }
 
 // NewBuilder creates a Builder
 func NewBuilder(units map[string]string, dimensions map[string]string, percentiles []float64,
-	storageResolution int64) *Builder {
+	storageResolution int64, opts ...Option) *Builder {
 
 	var dims []*cloudwatch.Dimension = nil
 	n := len(dimensions)
@@ -55,107 +111,242 @@ func NewBuilder(units map[string]string, dimensions map[string]string, percentil
 		}
 	}
 
-	return &Builder{
+	b := &Builder{
 		units:             units,
 		dimensions:        dims,
 		percentiles:       percentiles,
 		storageResolution: aws.Int64(storageResolution),
+		summary:           DefaultSummary,
+	}
+
+	for _, o := range opts {
+		o(b)
 	}
+
+	return b
 }
 
-func (b *Builder) buildDatum(name string, value float64, unit string, t time.Time) *cloudwatch.MetricDatum {
+func (b *Builder) buildDatum(name string, value float64, unit string, t time.Time,
+	storageResolution *int64) *cloudwatch.MetricDatum {
 	return &cloudwatch.MetricDatum{
-		MetricName:        aws.String(name),
+		MetricName:        aws.String(b.names.Transform(name)),
 		Value:             aws.Float64(value),
 		Unit:              aws.String(unit),
 		Dimensions:        b.dimensions,
 		Timestamp:         aws.Time(t.UTC()),
-		StorageResolution: b.storageResolution,
+		StorageResolution: storageResolution,
+	}
+}
+
+func (b *Builder) getStorageResolution(name string) *int64 {
+	if r, ok := FindResolutionRule(b.resolutionRules, name); ok {
+		return aws.Int64(r.Resolution)
 	}
+	return b.storageResolution
 }
 
 func (b *Builder) getMetricUnit(name string, defaultUnit string) string {
+	if b.unitsByTransformedName {
+		name = b.names.Transform(name)
+	}
 	if val, ok := b.units[name]; ok {
 		return val
 	}
+	for _, r := range b.unitRules {
+		if r.Matches(name) {
+			return r.Unit
+		}
+	}
 	return defaultUnit
 }
 
+// getHistogramSource returns the unit a histogram is recorded in, declared by the conversion
+// rules, and its default unit: the declared one, or milliseconds for nanoseconds as for timers.
+// Histograms without rule are not converted and default to Count
+func (b *Builder) getHistogramSource(name string) (from string, defaultUnit string) {
+	for _, r := range b.conversionRules {
+		if r.Matches(name) {
+			if r.From == UnitNanoseconds {
+				return r.From, cloudwatch.StandardUnitMilliseconds
+			}
+			return r.From, r.From
+		}
+	}
+	return "", cloudwatch.StandardUnitCount
+}
+
+// getConversion returns the unit of the summary of a histogram or timer and the conversion of
+// its values, recorded in from, to this unit. Values are published as is when from is empty or
+// the units are not convertible
+func (b *Builder) getConversion(name string, from string, defaultUnit string) (string, func(float64) float64) {
+	unit := b.getMetricUnit(name, defaultUnit)
+	if from == "" {
+		return unit, func(v float64) float64 { return v }
+	}
+	return unit, func(v float64) float64 {
+		res, _ := Convert(v, from, unit)
+		return res
+	}
+}
+
+func (b *Builder) getSummary(name string) Summary {
+	for _, r := range b.summaryRules {
+		if r.Matches(name) {
+			return r.Summary
+		}
+	}
+	return b.summary
+}
+
+// buildSummary generates the data of the summary fields of a histogram or timer snapshot. Every
+// field but the count is converted to unit
+func (b *Builder) buildSummary(metric summarized, name string, unit string, convertFunc func(float64) float64,
+	t time.Time) []*cloudwatch.MetricDatum {
+	if metric.Count() == 0 {
+		return nil
+	}
+
+	sr := b.getStorageResolution(name)
+	summary := b.getSummary(name)
+	res := []*cloudwatch.MetricDatum{}
+	add := func(suffix string, v float64) {
+		res = append(res, b.buildDatum(fmt.Sprintf("%s.%s", name, suffix), convertFunc(v), unit, t, sr))
+	}
+
+	if summary.Has(SummaryCount) {
+		n := fmt.Sprintf("%s.count", name)
+		res = append(res, b.buildDatum(n, float64(metric.Count()), cloudwatch.StandardUnitCount, t, sr))
+	}
+	if summary.Has(SummaryPercentiles) {
+		for index, val := range metric.Percentiles(b.percentiles) {
+			add(fmt.Sprintf("p%v", int(b.percentiles[index]*100)), val)
+		}
+	}
+	if summary.Has(SummaryMean) {
+		add("mean", metric.Mean())
+	}
+	if summary.Has(SummaryStdDev) {
+		add("stddev", metric.StdDev())
+	}
+	if summary.Has(SummaryMin) {
+		add("min", float64(metric.Min()))
+	}
+	if summary.Has(SummaryMax) {
+		add("max", float64(metric.Max()))
+	}
+	if summary.Has(SummarySum) {
+		add("sum", float64(metric.Sum()))
+	}
+
+	return res
+}
+
 // BuildCounterData generates data from a Counter
 func (b *Builder) BuildCounterData(v metrics.Counter, name string) []*cloudwatch.MetricDatum {
+	return b.BuildCounterDataAt(v, name, time.Now())
+}
+
+// BuildCounterDataAt generates data from a Counter, stamped with t
+func (b *Builder) BuildCounterDataAt(v metrics.Counter, name string, t time.Time) []*cloudwatch.MetricDatum {
 	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
-	datum := b.buildDatum(name, float64(v.Count()), unit, time.Now())
+	datum := b.buildDatum(name, float64(v.Count()), unit, t, b.getStorageResolution(name))
 	return []*cloudwatch.MetricDatum{datum}
 }
 
 // BuildGaugeData generates data from a Gauge
 func (b *Builder) BuildGaugeData(v metrics.Gauge, name string) []*cloudwatch.MetricDatum {
+	return b.BuildGaugeDataAt(v, name, time.Now())
+}
+
+// BuildGaugeDataAt generates data from a Gauge, stamped with t
+func (b *Builder) BuildGaugeDataAt(v metrics.Gauge, name string, t time.Time) []*cloudwatch.MetricDatum {
 	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
-	datum := b.buildDatum(name, float64(v.Value()), unit, time.Now())
+	datum := b.buildDatum(name, float64(v.Value()), unit, t, b.getStorageResolution(name))
 	return []*cloudwatch.MetricDatum{datum}
 }
 
 // BuildGaugeFloat64Data generates data from a GaugeFloat64
 func (b *Builder) BuildGaugeFloat64Data(v metrics.GaugeFloat64, name string) []*cloudwatch.MetricDatum {
+	return b.BuildGaugeFloat64DataAt(v, name, time.Now())
+}
+
+// BuildGaugeFloat64DataAt generates data from a GaugeFloat64, stamped with t
+func (b *Builder) BuildGaugeFloat64DataAt(v metrics.GaugeFloat64, name string, t time.Time) []*cloudwatch.MetricDatum {
 	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
-	datum := b.buildDatum(name, float64(v.Value()), unit, time.Now())
+	datum := b.buildDatum(name, float64(v.Value()), unit, t, b.getStorageResolution(name))
 	return []*cloudwatch.MetricDatum{datum}
 }
 
 // BuildMeterData generates data from a Meter
 func (b *Builder) BuildMeterData(v metrics.Meter, name string) []*cloudwatch.MetricDatum {
+	return b.BuildMeterDataAt(v, name, time.Now())
+}
+
+// BuildMeterDataAt generates data from a Meter, stamped with t
+func (b *Builder) BuildMeterDataAt(v metrics.Meter, name string, t time.Time) []*cloudwatch.MetricDatum {
 	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
-	datum := b.buildDatum(name, float64(v.Rate1()), unit, time.Now())
+	datum := b.buildDatum(name, float64(v.Rate1()), unit, t, b.getStorageResolution(name))
 	return []*cloudwatch.MetricDatum{datum}
 }
 
 // BuildHistogramData generates data from an Histogram
 func (b *Builder) BuildHistogramData(v metrics.Histogram, name string) []*cloudwatch.MetricDatum {
-	metric := v.Snapshot()
-	if metric.Count() == 0 {
-		return nil
-	}
-
-	t := time.Now()
-	// Build Count datum
-	datum := b.buildDatum(fmt.Sprintf("%s.count", name), float64(metric.Count()), cloudwatch.StandardUnitCount, t)
-	res := []*cloudwatch.MetricDatum{datum}
-
-	// Build Percentiles data
-	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
-	for index, val := range metric.Percentiles(b.percentiles) {
-		n := fmt.Sprintf("%s.p%v", name, int(b.percentiles[index]*100))
-		datum := b.buildDatum(n, val, unit, t)
-		res = append(res, datum)
-	}
+	return b.BuildHistogramDataAt(v, name, time.Now())
+}
 
-	return res
+// BuildHistogramDataAt generates data from an Histogram, stamped with t
+func (b *Builder) BuildHistogramDataAt(v metrics.Histogram, name string, t time.Time) []*cloudwatch.MetricDatum {
+	from, defaultUnit := b.getHistogramSource(name)
+	unit, convertFunc := b.getConversion(name, from, defaultUnit)
+	return b.buildSummary(v.Snapshot(), name, unit, convertFunc, t)
 }
 
 // BuildTimerData generates data from a Timer
 func (b *Builder) BuildTimerData(v metrics.Timer, name string) []*cloudwatch.MetricDatum {
-	metric := v.Snapshot()
-	if metric.Count() == 0 {
-		return nil
-	}
+	return b.BuildTimerDataAt(v, name, time.Now())
+}
 
-	t := time.Now()
-	// Build Count datum
-	datum := b.buildDatum(fmt.Sprintf("%s.count", name), float64(metric.Count()), cloudwatch.StandardUnitCount, t)
-	res := []*cloudwatch.MetricDatum{datum}
+// BuildTimerDataAt generates data from a Timer, stamped with t
+func (b *Builder) BuildTimerDataAt(v metrics.Timer, name string, t time.Time) []*cloudwatch.MetricDatum {
+	unit, convertFunc := b.getConversion(name, UnitNanoseconds, cloudwatch.StandardUnitMilliseconds)
+	return b.buildSummary(v.Snapshot(), name, unit, convertFunc, t)
+}
 
-	// Build Percentiles data
-	unit := b.getMetricUnit(name, cloudwatch.StandardUnitMilliseconds)
-	convertFunc := identity
-	if cf, ok := convertDuration[unit]; ok {
-		convertFunc = cf
-	}
+// BuildHealthcheckData generates data from a Healthcheck, 1 when healthy and 0 otherwise
+func (b *Builder) BuildHealthcheckData(v metrics.Healthcheck, name string) []*cloudwatch.MetricDatum {
+	return b.BuildHealthcheckDataAt(v, name, time.Now())
+}
 
-	for index, val := range metric.Percentiles(b.percentiles) {
-		n := fmt.Sprintf("%s.p%v", name, int(b.percentiles[index]*100))
-		datum := b.buildDatum(n, convertFunc(val), unit, t)
-		res = append(res, datum)
+// BuildHealthcheckDataAt generates data from a Healthcheck, stamped with t
+func (b *Builder) BuildHealthcheckDataAt(v metrics.Healthcheck, name string, t time.Time) []*cloudwatch.MetricDatum {
+	value := 1.0
+	if v.Error() != nil {
+		value = 0
 	}
 
-	return res
+	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCount)
+	datum := b.buildDatum(name, value, unit, t, b.getStorageResolution(name))
+	return []*cloudwatch.MetricDatum{datum}
+}
+
+// BuildEWMAData generates data from an EWMA
+func (b *Builder) BuildEWMAData(v metrics.EWMA, name string) []*cloudwatch.MetricDatum {
+	return b.BuildEWMADataAt(v, name, time.Now())
+}
+
+// BuildEWMADataAt generates data from an EWMA, stamped with t
+func (b *Builder) BuildEWMADataAt(v metrics.EWMA, name string, t time.Time) []*cloudwatch.MetricDatum {
+	unit := b.getMetricUnit(name, cloudwatch.StandardUnitCountSecond)
+	datum := b.buildDatum(name, v.Snapshot().Rate(), unit, t, b.getStorageResolution(name))
+	return []*cloudwatch.MetricDatum{datum}
+}
+
+// BuildValueDataAt generates data from a computed value, such as a derived metric, stamped with
+// t. An empty unit is looked up as for the other metrics and defaults to None
+func (b *Builder) BuildValueDataAt(name string, v float64, unit string, t time.Time) []*cloudwatch.MetricDatum {
+	if unit == "" {
+		unit = b.getMetricUnit(name, cloudwatch.StandardUnitNone)
+	}
+	datum := b.buildDatum(name, v, unit, t, b.getStorageResolution(name))
+	return []*cloudwatch.MetricDatum{datum}
 }
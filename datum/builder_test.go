@@ -15,6 +15,8 @@ package datum
 //	limitations under the License
 
 import (
+	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -303,3 +305,178 @@ func TestBuilder__BuildTimerData(t *testing.T) {
 		assert.Empty(t, data)
 	})
 }
+
+func TestBuilder__NameTransformer(t *testing.T) {
+	name := "my.metric"
+	m := metrics.NewTimer()
+	m.Update(time.Duration(200) * time.Millisecond)
+
+	tr := &NameTransformer{Prefix: "app_", Sanitizer: DefaultSanitizer, SanitizeReplacement: "_", Rules: []RewriteRule{
+		{Pattern: regexp.MustCompile(`\.`), Replacement: "_"},
+	}}
+
+	t.Run("OK - Units use original name", func(t *testing.T) {
+		b := NewBuilder(
+			map[string]string{name: cloudwatch.StandardUnitSeconds},
+			nil,
+			[]float64{0.5},
+			30,
+			WithNameTransformer(tr),
+		)
+		data := b.BuildTimerData(m, name)
+
+		assert.Len(t, data, 2)
+		tmstp := data[0].Timestamp
+		assert.ElementsMatch(t, []*cloudwatch.MetricDatum{
+			{
+				MetricName:        aws.String("app_my_metric_count"),
+				Value:             aws.Float64(1),
+				Unit:              aws.String(cloudwatch.StandardUnitCount),
+				Timestamp:         tmstp,
+				StorageResolution: aws.Int64(30),
+			},
+			{
+				MetricName:        aws.String("app_my_metric_p50"),
+				Value:             aws.Float64(0.2),
+				Unit:              aws.String(cloudwatch.StandardUnitSeconds),
+				Timestamp:         tmstp,
+				StorageResolution: aws.Int64(30),
+			},
+		}, data)
+	})
+
+	t.Run("OK - Units use transformed name", func(t *testing.T) {
+		b := NewBuilder(
+			map[string]string{
+				name:            cloudwatch.StandardUnitSeconds,
+				"app_my_metric": cloudwatch.StandardUnitMicroseconds,
+			},
+			nil,
+			[]float64{0.5},
+			30,
+			WithNameTransformer(tr),
+			WithTransformedUnitLookup(),
+		)
+		data := b.BuildTimerData(m, name)
+
+		assert.Len(t, data, 2)
+		assert.Equal(t, "app_my_metric_p50", *data[1].MetricName)
+		assert.Equal(t, 200000.0, *data[1].Value)
+		assert.Equal(t, cloudwatch.StandardUnitMicroseconds, *data[1].Unit)
+	})
+}
+
+func TestBuilder__BuildHealthcheckData(t *testing.T) {
+	name := "my-metric"
+
+	t.Run("OK - Healthy", func(t *testing.T) {
+		m := metrics.NewHealthcheck(func(h metrics.Healthcheck) { h.Healthy() })
+		m.Check()
+
+		b := NewBuilder(nil, nil, nil, 30)
+		data := b.BuildHealthcheckData(m, name)
+
+		assert.Len(t, data, 1)
+		assert.Equal(t, &cloudwatch.MetricDatum{
+			MetricName:        &name,
+			Value:             aws.Float64(1),
+			Unit:              aws.String(cloudwatch.StandardUnitCount),
+			Timestamp:         data[0].Timestamp,
+			StorageResolution: aws.Int64(30),
+		}, data[0])
+	})
+
+	t.Run("OK - Unhealthy", func(t *testing.T) {
+		m := metrics.NewHealthcheck(func(h metrics.Healthcheck) { h.Unhealthy(errors.New("down")) })
+		m.Check()
+
+		b := NewBuilder(nil, nil, nil, 30)
+		data := b.BuildHealthcheckData(m, name)
+
+		assert.Len(t, data, 1)
+		assert.Equal(t, &cloudwatch.MetricDatum{
+			MetricName:        &name,
+			Value:             aws.Float64(0),
+			Unit:              aws.String(cloudwatch.StandardUnitCount),
+			Timestamp:         data[0].Timestamp,
+			StorageResolution: aws.Int64(30),
+		}, data[0])
+	})
+}
+
+func TestBuilder__BuildEWMAData(t *testing.T) {
+	name := "my-metric"
+	m := metrics.NewEWMA1()
+	m.Update(300)
+	m.Tick()
+
+	t.Run("OK - No config", func(t *testing.T) {
+		b := NewBuilder(nil, nil, nil, 30)
+		data := b.BuildEWMAData(m, name)
+
+		assert.Len(t, data, 1)
+		assert.InDelta(t, 60, *data[0].Value, 1e-9)
+		assert.Equal(t, &cloudwatch.MetricDatum{
+			MetricName:        &name,
+			Value:             data[0].Value,
+			Unit:              aws.String(cloudwatch.StandardUnitCountSecond),
+			Timestamp:         data[0].Timestamp,
+			StorageResolution: aws.Int64(30),
+		}, data[0])
+	})
+
+	t.Run("OK - With unit", func(t *testing.T) {
+		b := NewBuilder(map[string]string{name: cloudwatch.StandardUnitBytesSecond}, nil, nil, 30)
+		data := b.BuildEWMAData(m, name)
+
+		assert.Len(t, data, 1)
+		assert.Equal(t, cloudwatch.StandardUnitBytesSecond, *data[0].Unit)
+	})
+}
+
+func TestBuilder__BuildDataAt(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuilder(nil, nil, []float64{.5, .99}, 60)
+
+	timer := metrics.NewTimer()
+	timer.Update(time.Second)
+	histogram := metrics.NewHistogram(metrics.NewUniformSample(10))
+	histogram.Update(1)
+
+	data := b.BuildCounterDataAt(metrics.NewCounter(), "counter", ts)
+	data = append(data, b.BuildGaugeDataAt(metrics.NewGauge(), "gauge", ts)...)
+	data = append(data, b.BuildGaugeFloat64DataAt(metrics.NewGaugeFloat64(), "gauge-float64", ts)...)
+	data = append(data, b.BuildMeterDataAt(metrics.NewMeter(), "meter", ts)...)
+	data = append(data, b.BuildHistogramDataAt(histogram, "histogram", ts)...)
+	data = append(data, b.BuildTimerDataAt(timer, "timer", ts)...)
+	data = append(data, b.BuildHealthcheckDataAt(metrics.NewHealthcheck(nil), "healthcheck", ts)...)
+	data = append(data, b.BuildEWMADataAt(metrics.NewEWMA1(), "ewma", ts)...)
+
+	assert.Len(t, data, 12)
+	for _, d := range data {
+		assert.Equal(t, ts, *d.Timestamp, *d.MetricName)
+	}
+}
+
+func TestBuilder__BuildValueDataAt(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuilder(map[string]string{"queue.size": cloudwatch.StandardUnitCount}, nil, nil, 60)
+
+	t.Run("OK - Declared unit", func(t *testing.T) {
+		data := b.BuildValueDataAt("errors.rate", 12.5, cloudwatch.StandardUnitPercent, ts)
+		assert.Len(t, data, 1)
+		assert.Equal(t, 12.5, *data[0].Value)
+		assert.Equal(t, cloudwatch.StandardUnitPercent, *data[0].Unit)
+		assert.Equal(t, ts, *data[0].Timestamp)
+	})
+
+	t.Run("OK - Looked up unit", func(t *testing.T) {
+		data := b.BuildValueDataAt("queue.size", 3, "", ts)
+		assert.Len(t, data, 1)
+		assert.Equal(t, cloudwatch.StandardUnitCount, *data[0].Unit)
+
+		data = b.BuildValueDataAt("other", 3, "", ts)
+		assert.Len(t, data, 1)
+		assert.Equal(t, cloudwatch.StandardUnitNone, *data[0].Unit)
+	})
+}
@@ -0,0 +1,133 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// UnitNanoseconds is the unit of the durations recorded by timers. It is not a CloudWatch unit,
+// so it can only be declared as the source unit of a conversion
+const UnitNanoseconds = "Nanoseconds"
+
+// Families of convertible units
+const (
+	durationUnits = iota
+	sizeUnits
+	rateUnits
+)
+
+// convertible gives the family of a unit and its value in the base unit of the family:
+// nanoseconds, bytes and bytes per second. Data sizes use powers of 1024
+type convertible struct {
+	family int
+	factor float64
+}
+
+const (
+	kibi = 1 << 10
+	mebi = 1 << 20
+	gibi = 1 << 30
+	tebi = 1 << 40
+)
+
+var conversions = map[string]convertible{
+	UnitNanoseconds:                        {durationUnits, 1},
+	cloudwatch.StandardUnitMicroseconds:    {durationUnits, float64(time.Microsecond)},
+	cloudwatch.StandardUnitMilliseconds:    {durationUnits, float64(time.Millisecond)},
+	cloudwatch.StandardUnitSeconds:         {durationUnits, float64(time.Second)},
+	cloudwatch.StandardUnitBits:            {sizeUnits, 1. / 8},
+	cloudwatch.StandardUnitKilobits:        {sizeUnits, kibi / 8},
+	cloudwatch.StandardUnitMegabits:        {sizeUnits, mebi / 8},
+	cloudwatch.StandardUnitGigabits:        {sizeUnits, gibi / 8},
+	cloudwatch.StandardUnitTerabits:        {sizeUnits, tebi / 8},
+	cloudwatch.StandardUnitBytes:           {sizeUnits, 1},
+	cloudwatch.StandardUnitKilobytes:       {sizeUnits, kibi},
+	cloudwatch.StandardUnitMegabytes:       {sizeUnits, mebi},
+	cloudwatch.StandardUnitGigabytes:       {sizeUnits, gibi},
+	cloudwatch.StandardUnitTerabytes:       {sizeUnits, tebi},
+	cloudwatch.StandardUnitBitsSecond:      {rateUnits, 1. / 8},
+	cloudwatch.StandardUnitKilobitsSecond:  {rateUnits, kibi / 8},
+	cloudwatch.StandardUnitMegabitsSecond:  {rateUnits, mebi / 8},
+	cloudwatch.StandardUnitGigabitsSecond:  {rateUnits, gibi / 8},
+	cloudwatch.StandardUnitTerabitsSecond:  {rateUnits, tebi / 8},
+	cloudwatch.StandardUnitBytesSecond:     {rateUnits, 1},
+	cloudwatch.StandardUnitKilobytesSecond: {rateUnits, kibi},
+	cloudwatch.StandardUnitMegabytesSecond: {rateUnits, mebi},
+	cloudwatch.StandardUnitGigabytesSecond: {rateUnits, gibi},
+	cloudwatch.StandardUnitTerabytesSecond: {rateUnits, tebi},
+}
+
+// Convert converts v from one unit to another of the same family: durations, data sizes or data
+// rates. It returns false, and v unchanged, when the units cannot be converted
+func Convert(v float64, from, to string) (float64, bool) {
+	f, ok := conversions[from]
+	if !ok {
+		return v, false
+	}
+	t, ok := conversions[to]
+	if !ok || f.family != t.family {
+		return v, false
+	}
+	return v * f.factor / t.factor, true
+}
+
+// ConversionRule declares the unit in which the values of the matching histograms are recorded.
+// Their summary is converted from this unit to the unit of the metric, given by the units map or
+// the unit rules. Timers always record nanoseconds and ignore the rules
+type ConversionRule struct {
+	Matcher
+	From string
+}
+
+// NameConversion creates a ConversionRule matching the metric name exactly
+func NameConversion(name string, from string) ConversionRule {
+	return ConversionRule{Matcher: MatchName(name), From: from}
+}
+
+// SuffixConversion creates a ConversionRule matching metric names ending with suffix
+func SuffixConversion(suffix string, from string) ConversionRule {
+	return ConversionRule{Matcher: MatchSuffix(suffix), From: from}
+}
+
+// PrefixConversion creates a ConversionRule matching metric names starting with prefix
+func PrefixConversion(prefix string, from string) ConversionRule {
+	return ConversionRule{Matcher: MatchPrefix(prefix), From: from}
+}
+
+// RegexpConversion creates a ConversionRule matching metric names matched by pattern
+func RegexpConversion(pattern *regexp.Regexp, from string) ConversionRule {
+	return ConversionRule{Matcher: MatchRegexp(pattern), From: from}
+}
+
+// ValidateConversions checks that every rule declares a convertible unit
+func ValidateConversions(rules []ConversionRule) error {
+	invalid := []string{}
+	for _, r := range rules {
+		if _, ok := conversions[r.From]; !ok {
+			invalid = append(invalid, fmt.Sprintf("%q for %s", r.From, r.desc))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid source unit(s): %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
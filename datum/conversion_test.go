@@ -0,0 +1,141 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        float64
+		from, to string
+		res      float64
+		ok       bool
+	}{
+		{"OK - Nanoseconds to milliseconds", 2e6, UnitNanoseconds, cloudwatch.StandardUnitMilliseconds, 2, true},
+		{"OK - Seconds to microseconds", 1.5, cloudwatch.StandardUnitSeconds, cloudwatch.StandardUnitMicroseconds, 1.5e6, true},
+		{"OK - Bytes to kilobytes", 2048, cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitKilobytes, 2, true},
+		{"OK - Bytes to bits", 2, cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitBits, 16, true},
+		{"OK - Rates", 1, cloudwatch.StandardUnitMegabytesSecond, cloudwatch.StandardUnitKilobytesSecond, 1024, true},
+		{"OK - Same unit", 3, cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitBytes, 3, true},
+		{"NOK - Different families", 3, cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitSeconds, 3, false},
+		{"NOK - Size to rate", 3, cloudwatch.StandardUnitBytes, cloudwatch.StandardUnitBytesSecond, 3, false},
+		{"NOK - Not convertible", 3, cloudwatch.StandardUnitCount, cloudwatch.StandardUnitPercent, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, ok := Convert(tt.v, tt.from, tt.to)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestValidateConversions(t *testing.T) {
+	assert.NoError(t, ValidateConversions([]ConversionRule{
+		SuffixConversion(".ns", UnitNanoseconds),
+		PrefixConversion("size.", cloudwatch.StandardUnitBytes),
+	}))
+	assert.EqualError(t, ValidateConversions([]ConversionRule{
+		NameConversion("latency", "ns"),
+		RegexpConversion(regexp.MustCompile(`^queue\.`), cloudwatch.StandardUnitCount),
+	}), `invalid source unit(s): "ns" for name "latency", "Count" for regexp "^queue\\."`)
+}
+
+func TestBuilder__Conversions(t *testing.T) {
+	units := map[string]string{
+		"db.latency":   cloudwatch.StandardUnitMicroseconds,
+		"payload.size": cloudwatch.StandardUnitKilobytes,
+		"timer.s":      cloudwatch.StandardUnitSeconds,
+		"mismatch":     cloudwatch.StandardUnitPercent,
+	}
+	b := NewBuilder(units, nil, []float64{.5}, 60, WithConversionRules(
+		PrefixConversion("db.", UnitNanoseconds),
+		SuffixConversion(".size", cloudwatch.StandardUnitBytes),
+		NameConversion("mismatch", cloudwatch.StandardUnitBytes),
+		NameConversion("timer.s", cloudwatch.StandardUnitMilliseconds),
+	))
+
+	histogram := func(v int64) metrics.Histogram {
+		h := metrics.NewHistogram(metrics.NewUniformSample(10))
+		h.Update(v)
+		return h
+	}
+	percentile := func(t *testing.T, data []*cloudwatch.MetricDatum) (float64, string) {
+		require.Len(t, data, 2)
+		return *data[1].Value, *data[1].Unit
+	}
+
+	tests := []struct {
+		name   string
+		metric string
+		v      int64
+		res    float64
+		unit   string
+	}{
+		{"OK - Declared target unit", "db.latency", 3000, 3, cloudwatch.StandardUnitMicroseconds},
+		{"OK - Nanoseconds default to milliseconds", "db.query", 3e6, 3, cloudwatch.StandardUnitMilliseconds},
+		{"OK - Data size", "payload.size", 4096, 4, cloudwatch.StandardUnitKilobytes},
+		{"OK - Source unit by default", "queue.size", 4096, 4096, cloudwatch.StandardUnitBytes},
+		{"OK - No conversion", "other", 42, 42, cloudwatch.StandardUnitCount},
+		{"NOK - Incompatible units published as is", "mismatch", 42, 42, cloudwatch.StandardUnitPercent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, unit := percentile(t, b.BuildHistogramData(histogram(tt.v), tt.metric))
+			assert.Equal(t, tt.res, v)
+			assert.Equal(t, tt.unit, unit)
+		})
+	}
+
+	t.Run("OK - Timers", func(t *testing.T) {
+		timer := metrics.NewTimer()
+		timer.Update(2 * time.Second)
+		v, unit := percentile(t, b.BuildTimerData(timer, "timer.ms"))
+		assert.Equal(t, 2000.0, v)
+		assert.Equal(t, cloudwatch.StandardUnitMilliseconds, unit)
+
+		// Timers record nanoseconds whatever the conversion rules
+		timer = metrics.NewTimer()
+		timer.Update(1500 * time.Millisecond)
+		v, unit = percentile(t, b.BuildTimerData(timer, "timer.s"))
+		assert.Equal(t, 1.5, v)
+		assert.Equal(t, cloudwatch.StandardUnitSeconds, unit)
+	})
+
+	t.Run("OK - Histogram rules do not apply to timers", func(t *testing.T) {
+		b := NewBuilder(nil, nil, []float64{.5}, 60,
+			WithConversionRules(PrefixConversion("db.", cloudwatch.StandardUnitMicroseconds)))
+
+		timer := metrics.NewTimer()
+		timer.Update(2 * time.Millisecond)
+		v, unit := percentile(t, b.BuildTimerData(timer, "db.query"))
+		assert.Equal(t, 2.0, v)
+		assert.Equal(t, cloudwatch.StandardUnitMilliseconds, unit)
+
+		v, unit = percentile(t, b.BuildHistogramData(histogram(2000), "db.rows"))
+		assert.Equal(t, 2000.0, v)
+		assert.Equal(t, cloudwatch.StandardUnitMicroseconds, unit)
+	})
+}
@@ -0,0 +1,98 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Priorities of the name matchers, lower values win
+const (
+	namePriority = iota
+	suffixPriority
+	prefixPriority
+	regexpPriority
+)
+
+// Matcher selects metrics by name for the rule embedding it. When several rules of a kind match
+// a metric, an exact name wins over a suffix, a suffix over a prefix and a prefix over a regexp,
+// and rules of the same kind keep their declaration order
+type Matcher struct {
+	priority int
+	desc     string
+	match    func(name string) bool
+}
+
+// MatchName creates a Matcher matching the metric name exactly
+func MatchName(n string) Matcher {
+	return Matcher{
+		priority: namePriority,
+		desc:     fmt.Sprintf("name %q", n),
+		match:    func(name string) bool { return name == n },
+	}
+}
+
+// MatchSuffix creates a Matcher matching metric names ending with suffix
+func MatchSuffix(suffix string) Matcher {
+	return Matcher{
+		priority: suffixPriority,
+		desc:     fmt.Sprintf("suffix %q", suffix),
+		match:    func(name string) bool { return strings.HasSuffix(name, suffix) },
+	}
+}
+
+// MatchPrefix creates a Matcher matching metric names starting with prefix
+func MatchPrefix(prefix string) Matcher {
+	return Matcher{
+		priority: prefixPriority,
+		desc:     fmt.Sprintf("prefix %q", prefix),
+		match:    func(name string) bool { return strings.HasPrefix(name, prefix) },
+	}
+}
+
+// MatchRegexp creates a Matcher matching metric names matched by pattern
+func MatchRegexp(pattern *regexp.Regexp) Matcher {
+	return Matcher{
+		priority: regexpPriority,
+		desc:     fmt.Sprintf("regexp %q", pattern.String()),
+		match:    pattern.MatchString,
+	}
+}
+
+// Matches reports whether the rule applies to the metric name
+func (m Matcher) Matches(name string) bool {
+	return m.match != nil && m.match(name)
+}
+
+// Priority orders the rules when several match, lower values win
+func (m Matcher) Priority() int {
+	return m.priority
+}
+
+// Describe returns a human readable description of what the Matcher selects
+func (m Matcher) Describe() string {
+	return m.desc
+}
+
+// SortByPriority stably sorts rules, a slice of rules embedding a Matcher, by precedence.
+// matcher returns the Matcher of the i-th rule
+func SortByPriority(rules interface{}, matcher func(i int) Matcher) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return matcher(i).priority < matcher(j).priority
+	})
+}
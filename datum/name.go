@@ -0,0 +1,79 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultSanitizer matches the characters replaced when sanitization is enabled without a
+// custom pattern
+var DefaultSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_\-.:/#]`)
+
+// NameCase is the case conversion applied to metric names
+type NameCase int
+
+const (
+	// CasePreserve keeps metric names as they are
+	CasePreserve NameCase = iota
+	// CaseLower converts metric names to lower case
+	CaseLower
+	// CaseUpper converts metric names to upper case
+	CaseUpper
+)
+
+// RewriteRule replaces every match of Pattern in a metric name with Replacement, which can
+// reference capture groups as in regexp.ReplaceAllString
+type RewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// NameTransformer rewrites metric names before they are sent to CloudWatch.
+// Steps are applied in this order: rewrite rules, sanitization, case conversion, and finally
+// prefix and suffix.
+type NameTransformer struct {
+	Prefix              string
+	Suffix              string
+	Rules               []RewriteRule
+	Sanitizer           *regexp.Regexp
+	SanitizeReplacement string
+	Case                NameCase
+}
+
+// Transform applies the transformation pipeline to name
+func (t *NameTransformer) Transform(name string) string {
+	if t == nil {
+		return name
+	}
+
+	for _, r := range t.Rules {
+		name = r.Pattern.ReplaceAllString(name, r.Replacement)
+	}
+
+	if t.Sanitizer != nil {
+		name = t.Sanitizer.ReplaceAllString(name, t.SanitizeReplacement)
+	}
+
+	switch t.Case {
+	case CaseLower:
+		name = strings.ToLower(name)
+	case CaseUpper:
+		name = strings.ToUpper(name)
+	}
+
+	return t.Prefix + name + t.Suffix
+}
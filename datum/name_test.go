@@ -0,0 +1,63 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameTransformer__Transform(t *testing.T) {
+	t.Run("OK - Nil transformer", func(t *testing.T) {
+		var tr *NameTransformer
+		assert.Equal(t, "my.metric", tr.Transform("my.metric"))
+	})
+
+	t.Run("OK - Empty transformer", func(t *testing.T) {
+		tr := &NameTransformer{}
+		assert.Equal(t, "my.metric", tr.Transform("my.metric"))
+	})
+
+	t.Run("OK - Prefix and suffix", func(t *testing.T) {
+		tr := &NameTransformer{Prefix: "app.", Suffix: ".v1"}
+		assert.Equal(t, "app.my.metric.v1", tr.Transform("my.metric"))
+	})
+
+	t.Run("OK - Default sanitizer", func(t *testing.T) {
+		tr := &NameTransformer{Sanitizer: DefaultSanitizer, SanitizeReplacement: "_"}
+		assert.Equal(t, "my_metric__.count", tr.Transform("my metric é.count"))
+	})
+
+	t.Run("OK - Case conversion", func(t *testing.T) {
+		assert.Equal(t, "my.metric", (&NameTransformer{Case: CaseLower}).Transform("My.Metric"))
+		assert.Equal(t, "MY.METRIC", (&NameTransformer{Case: CaseUpper}).Transform("My.Metric"))
+	})
+
+	t.Run("OK - All steps in order", func(t *testing.T) {
+		tr := &NameTransformer{
+			Prefix: "App/",
+			Rules: []RewriteRule{
+				{Pattern: regexp.MustCompile(`^http\.(\w+)`), Replacement: "Http.$1"},
+				{Pattern: regexp.MustCompile(`/`), Replacement: "."},
+			},
+			Sanitizer:           regexp.MustCompile(`\.`),
+			SanitizeReplacement: "_",
+			Case:                CaseLower,
+		}
+		assert.Equal(t, "App/http_requests_users_count", tr.Transform("http.requests/users.count"))
+	})
+}
@@ -0,0 +1,110 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Storage resolutions supported by CloudWatch, in seconds
+const (
+	HighResolution     int64 = 1
+	StandardResolution int64 = 60
+)
+
+// ResolutionRule assigns a storage resolution to every metric whose name matches the rule
+type ResolutionRule struct {
+	Matcher
+	Resolution int64
+	// SampleInterval, when set, makes the publisher sample the metric at this cadence and send
+	// every sample on flush instead of a single datum
+	SampleInterval time.Duration
+	// Aggregate, with a SampleInterval, sends a single StatisticSet datum (minimum, maximum, sum
+	// and count of the samples) on flush instead of every sample
+	Aggregate bool
+}
+
+// NameResolution creates a ResolutionRule matching the metric name exactly
+func NameResolution(name string, resolution int64) ResolutionRule {
+	return ResolutionRule{Matcher: MatchName(name), Resolution: resolution}
+}
+
+// SuffixResolution creates a ResolutionRule matching metric names ending with suffix
+func SuffixResolution(suffix string, resolution int64) ResolutionRule {
+	return ResolutionRule{Matcher: MatchSuffix(suffix), Resolution: resolution}
+}
+
+// PrefixResolution creates a ResolutionRule matching metric names starting with prefix
+func PrefixResolution(prefix string, resolution int64) ResolutionRule {
+	return ResolutionRule{Matcher: MatchPrefix(prefix), Resolution: resolution}
+}
+
+// RegexpResolution creates a ResolutionRule matching metric names matched by pattern
+func RegexpResolution(pattern *regexp.Regexp, resolution int64) ResolutionRule {
+	return ResolutionRule{Matcher: MatchRegexp(pattern), Resolution: resolution}
+}
+
+// SampledEvery returns a copy of the rule sampling the matching metrics every interval
+func (r ResolutionRule) SampledEvery(interval time.Duration) ResolutionRule {
+	r.SampleInterval = interval
+	return r
+}
+
+// Aggregated returns a copy of the rule publishing the statistics of the samples, see Aggregate
+func (r ResolutionRule) Aggregated() ResolutionRule {
+	r.Aggregate = true
+	return r
+}
+
+// SortResolutionRules returns the rules ordered by precedence
+func SortResolutionRules(rules []ResolutionRule) []ResolutionRule {
+	sorted := append([]ResolutionRule(nil), rules...)
+	SortByPriority(sorted, func(i int) Matcher { return sorted[i].Matcher })
+	return sorted
+}
+
+// FindResolutionRule returns the first rule of sorted rules matching name
+func FindResolutionRule(rules []ResolutionRule, name string) (ResolutionRule, bool) {
+	for _, r := range rules {
+		if r.Matches(name) {
+			return r, true
+		}
+	}
+	return ResolutionRule{}, false
+}
+
+// ValidateResolutions checks that every rule uses a storage resolution supported by CloudWatch
+func ValidateResolutions(rules []ResolutionRule) error {
+	invalid := []string{}
+	for _, r := range rules {
+		if r.Resolution != HighResolution && r.Resolution != StandardResolution {
+			invalid = append(invalid, fmt.Sprintf("%d for %s", r.Resolution, r.desc))
+		}
+		if r.SampleInterval < 0 {
+			invalid = append(invalid, fmt.Sprintf("negative sample interval for %s", r.desc))
+		}
+		if r.Aggregate && r.SampleInterval <= 0 {
+			invalid = append(invalid, fmt.Sprintf("aggregation without sample interval for %s", r.desc))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid storage resolution(s): %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestFindResolutionRule(t *testing.T) {
+	rules := SortResolutionRules([]ResolutionRule{
+		RegexpResolution(regexp.MustCompile(`latency`), HighResolution),
+		PrefixResolution("http.", StandardResolution),
+		SuffixResolution(".latency", HighResolution).SampledEvery(time.Second),
+		NameResolution("http.db.latency", StandardResolution),
+	})
+
+	t.Run("OK - Exact name wins", func(t *testing.T) {
+		r, ok := FindResolutionRule(rules, "http.db.latency")
+		assert.True(t, ok)
+		assert.Equal(t, StandardResolution, r.Resolution)
+		assert.Zero(t, r.SampleInterval)
+	})
+
+	t.Run("OK - Suffix wins over prefix", func(t *testing.T) {
+		r, ok := FindResolutionRule(rules, "http.api.latency")
+		assert.True(t, ok)
+		assert.Equal(t, HighResolution, r.Resolution)
+		assert.Equal(t, time.Second, r.SampleInterval)
+	})
+
+	t.Run("OK - Prefix wins over regexp", func(t *testing.T) {
+		r, ok := FindResolutionRule(rules, "http.latency_ms")
+		assert.True(t, ok)
+		assert.Equal(t, StandardResolution, r.Resolution)
+	})
+
+	t.Run("OK - No match", func(t *testing.T) {
+		_, ok := FindResolutionRule(rules, "db.size")
+		assert.False(t, ok)
+	})
+}
+
+func TestValidateResolutions(t *testing.T) {
+	assert.NoError(t, ValidateResolutions([]ResolutionRule{
+		PrefixResolution("a", HighResolution),
+		PrefixResolution("b", StandardResolution),
+	}))
+
+	assert.EqualError(t, ValidateResolutions([]ResolutionRule{
+		PrefixResolution("a", 30),
+		SuffixResolution("b", HighResolution).SampledEvery(-time.Second),
+	}), `invalid storage resolution(s): 30 for prefix "a", negative sample interval for suffix "b"`)
+
+	assert.NoError(t, ValidateResolutions([]ResolutionRule{
+		NameResolution("queue.depth", StandardResolution).SampledEvery(time.Second).Aggregated(),
+	}))
+	assert.EqualError(t, ValidateResolutions([]ResolutionRule{
+		NameResolution("queue.depth", StandardResolution).Aggregated(),
+	}), `invalid storage resolution(s): aggregation without sample interval for name "queue.depth"`)
+}
+
+func TestBuilder__ResolutionRules(t *testing.T) {
+	m := metrics.NewCounter()
+	b := NewBuilder(nil, nil, nil, StandardResolution,
+		WithResolutionRules(PrefixResolution("latency.", HighResolution)),
+	)
+
+	data := b.BuildCounterData(m, "latency.db")
+	assert.Len(t, data, 1)
+	assert.Equal(t, aws.Int64(HighResolution), data[0].StorageResolution)
+
+	data = b.BuildCounterData(m, "requests")
+	assert.Len(t, data, 1)
+	assert.Equal(t, aws.Int64(StandardResolution), data[0].StorageResolution)
+}
@@ -0,0 +1,113 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Summary selects the statistics published for histograms and timers, as a combination of
+// fields. Each field is published as a datum named after the metric with the suffix of the field
+type Summary uint
+
+// Summary fields, and their suffixes
+const (
+	// SummaryCount publishes the number of values as <name>.count
+	SummaryCount Summary = 1 << iota
+	// SummaryPercentiles publishes every configured percentile as <name>.p<percentile>
+	SummaryPercentiles
+	// SummaryMean publishes the mean as <name>.mean
+	SummaryMean
+	// SummaryStdDev publishes the standard deviation as <name>.stddev
+	SummaryStdDev
+	// SummaryMin publishes the minimum as <name>.min
+	SummaryMin
+	// SummaryMax publishes the maximum as <name>.max
+	SummaryMax
+	// SummarySum publishes the sum as <name>.sum
+	SummarySum
+
+	// DefaultSummary is the count and the percentiles
+	DefaultSummary = SummaryCount | SummaryPercentiles
+	// FullSummary is every field
+	FullSummary = SummarySum<<1 - 1
+)
+
+// Has reports whether every field of f is in s
+func (s Summary) Has(f Summary) bool {
+	return s&f == f
+}
+
+// SummaryRule overrides the summary of the histograms and timers whose name matches the rule
+type SummaryRule struct {
+	Matcher
+	Summary Summary
+}
+
+// NameSummary creates a SummaryRule matching the metric name exactly
+func NameSummary(name string, summary Summary) SummaryRule {
+	return SummaryRule{Matcher: MatchName(name), Summary: summary}
+}
+
+// SuffixSummary creates a SummaryRule matching metric names ending with suffix
+func SuffixSummary(suffix string, summary Summary) SummaryRule {
+	return SummaryRule{Matcher: MatchSuffix(suffix), Summary: summary}
+}
+
+// PrefixSummary creates a SummaryRule matching metric names starting with prefix
+func PrefixSummary(prefix string, summary Summary) SummaryRule {
+	return SummaryRule{Matcher: MatchPrefix(prefix), Summary: summary}
+}
+
+// RegexpSummary creates a SummaryRule matching metric names matched by pattern
+func RegexpSummary(pattern *regexp.Regexp, summary Summary) SummaryRule {
+	return SummaryRule{Matcher: MatchRegexp(pattern), Summary: summary}
+}
+
+// ValidateSummary checks that summary selects at least one known field
+func ValidateSummary(summary Summary) error {
+	if summary == 0 || summary&^FullSummary != 0 {
+		return fmt.Errorf("invalid summary %#x", uint(summary))
+	}
+	return nil
+}
+
+// ValidateSummaries checks that every rule selects at least one known field
+func ValidateSummaries(rules []SummaryRule) error {
+	invalid := []string{}
+	for _, r := range rules {
+		if ValidateSummary(r.Summary) != nil {
+			invalid = append(invalid, fmt.Sprintf("%#x for %s", uint(r.Summary), r.desc))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid summary(ies): %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// summarized is the snapshot of a histogram or a timer
+type summarized interface {
+	Count() int64
+	Max() int64
+	Mean() float64
+	Min() int64
+	Percentiles([]float64) []float64
+	StdDev() float64
+	Sum() int64
+}
@@ -0,0 +1,115 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestValidateSummaries(t *testing.T) {
+	assert.NoError(t, ValidateSummary(DefaultSummary))
+	assert.NoError(t, ValidateSummary(FullSummary))
+	assert.EqualError(t, ValidateSummary(0), "invalid summary 0x0")
+	assert.EqualError(t, ValidateSummary(SummaryMean|1<<10), "invalid summary 0x404")
+
+	assert.NoError(t, ValidateSummaries([]SummaryRule{SuffixSummary(".latency", SummaryMax)}))
+	assert.EqualError(t, ValidateSummaries([]SummaryRule{
+		NameSummary("latency", 0),
+		RegexpSummary(regexp.MustCompile(`^db\.`), SummarySum),
+	}), `invalid summary(ies): 0x0 for name "latency"`)
+}
+
+func TestBuilder__Summary(t *testing.T) {
+	ts := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	histogram := metrics.NewHistogram(metrics.NewUniformSample(10))
+	for _, v := range []int64{2, 4, 6} {
+		histogram.Update(v)
+	}
+	timer := metrics.NewTimer()
+	for _, v := range []time.Duration{2, 4, 6} {
+		timer.Update(v * time.Millisecond)
+	}
+
+	type value struct {
+		name  string
+		value float64
+		unit  string
+	}
+	values := func(data []*cloudwatch.MetricDatum) []value {
+		res := []value{}
+		for _, d := range data {
+			res = append(res, value{*d.MetricName, *d.Value, *d.Unit})
+		}
+		return res
+	}
+
+	t.Run("OK - Default summary", func(t *testing.T) {
+		b := NewBuilder(nil, nil, []float64{.5}, 60)
+		assert.Equal(t, []value{
+			{"h.count", 3, cloudwatch.StandardUnitCount},
+			{"h.p50", 4, cloudwatch.StandardUnitCount},
+		}, values(b.BuildHistogramDataAt(histogram, "h", ts)))
+	})
+
+	t.Run("OK - Full summary", func(t *testing.T) {
+		b := NewBuilder(nil, nil, []float64{.5}, 60, WithSummary(FullSummary))
+		data := b.BuildTimerDataAt(timer, "t", ts)
+		require.Len(t, data, 7)
+		res := values(data)
+		assert.Equal(t, []value{
+			{"t.count", 3, cloudwatch.StandardUnitCount},
+			{"t.p50", 4, cloudwatch.StandardUnitMilliseconds},
+			{"t.mean", 4, cloudwatch.StandardUnitMilliseconds},
+		}, res[:3])
+		assert.Equal(t, "t.stddev", res[3].name)
+		assert.InDelta(t, 1.633, res[3].value, .001)
+		assert.Equal(t, cloudwatch.StandardUnitMilliseconds, res[3].unit)
+		assert.Equal(t, []value{
+			{"t.min", 2, cloudwatch.StandardUnitMilliseconds},
+			{"t.max", 6, cloudwatch.StandardUnitMilliseconds},
+			{"t.sum", 12, cloudwatch.StandardUnitMilliseconds},
+		}, res[4:])
+	})
+
+	t.Run("OK - Summary rules", func(t *testing.T) {
+		b := NewBuilder(map[string]string{"db.size": cloudwatch.StandardUnitKilobytes}, nil, []float64{.5}, 60,
+			WithSummary(SummaryCount),
+			WithConversionRules(PrefixConversion("db.", cloudwatch.StandardUnitBytes)),
+			WithSummaryRules(
+				PrefixSummary("db.", SummaryMin|SummaryMax),
+				NameSummary("db.size", SummarySum),
+			),
+		)
+
+		assert.Equal(t, []value{
+			{"h.count", 3, cloudwatch.StandardUnitCount},
+		}, values(b.BuildHistogramDataAt(histogram, "h", ts)))
+		assert.Equal(t, []value{
+			{"db.rows.min", 2, cloudwatch.StandardUnitBytes},
+			{"db.rows.max", 6, cloudwatch.StandardUnitBytes},
+		}, values(b.BuildHistogramDataAt(histogram, "db.rows", ts)))
+		assert.Equal(t, []value{
+			{"db.size.sum", 12. / 1024, cloudwatch.StandardUnitKilobytes},
+		}, values(b.BuildHistogramDataAt(histogram, "db.size", ts)))
+	})
+}
@@ -0,0 +1,78 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// UnitRule assigns a unit to every metric whose name matches the rule and is not listed in the
+// units map
+type UnitRule struct {
+	Matcher
+	Unit string
+}
+
+// SuffixUnit creates a UnitRule matching metric names ending with suffix
+func SuffixUnit(suffix string, unit string) UnitRule {
+	return UnitRule{Matcher: MatchSuffix(suffix), Unit: unit}
+}
+
+// PrefixUnit creates a UnitRule matching metric names starting with prefix
+func PrefixUnit(prefix string, unit string) UnitRule {
+	return UnitRule{Matcher: MatchPrefix(prefix), Unit: unit}
+}
+
+// RegexpUnit creates a UnitRule matching metric names matched by pattern
+func RegexpUnit(pattern *regexp.Regexp, unit string) UnitRule {
+	return UnitRule{Matcher: MatchRegexp(pattern), Unit: unit}
+}
+
+// IsValidUnit reports whether unit is one of the CloudWatch standard units
+func IsValidUnit(unit string) bool {
+	for _, u := range cloudwatch.StandardUnit_Values() {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateUnits checks that every configured unit is a CloudWatch standard unit
+func ValidateUnits(units map[string]string, rules []UnitRule) error {
+	invalid := []string{}
+	for name, unit := range units {
+		if !IsValidUnit(unit) {
+			invalid = append(invalid, fmt.Sprintf("%q for metric %q", unit, name))
+		}
+	}
+	sort.Strings(invalid)
+
+	for _, r := range rules {
+		if !IsValidUnit(r.Unit) {
+			invalid = append(invalid, fmt.Sprintf("%q for %s", r.Unit, r.desc))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid unit(s): %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitRule__Matches(t *testing.T) {
+	assert.True(t, SuffixUnit(".bytes", cloudwatch.StandardUnitBytes).Matches("resp.bytes"))
+	assert.False(t, SuffixUnit(".bytes", cloudwatch.StandardUnitBytes).Matches("bytes.resp"))
+	assert.True(t, PrefixUnit("latency.", cloudwatch.StandardUnitMilliseconds).Matches("latency.db"))
+	assert.False(t, PrefixUnit("latency.", cloudwatch.StandardUnitMilliseconds).Matches("db.latency"))
+	assert.True(t, RegexpUnit(regexp.MustCompile(`\.size$`), cloudwatch.StandardUnitKilobytes).Matches("queue.size"))
+	assert.False(t, UnitRule{Unit: cloudwatch.StandardUnitCount}.Matches("anything"))
+}
+
+func TestBuilder__UnitRules(t *testing.T) {
+	b := NewBuilder(
+		map[string]string{"http.bytes": cloudwatch.StandardUnitKilobytes},
+		nil, nil, 60,
+		WithUnitRules(
+			RegexpUnit(regexp.MustCompile(`^http\.`), cloudwatch.StandardUnitPercent),
+			PrefixUnit("http.", cloudwatch.StandardUnitSeconds),
+			SuffixUnit(".bytes", cloudwatch.StandardUnitBytes),
+		),
+	)
+
+	t.Run("OK - Exact name wins", func(t *testing.T) {
+		assert.Equal(t, cloudwatch.StandardUnitKilobytes, b.getMetricUnit("http.bytes", cloudwatch.StandardUnitCount))
+	})
+
+	t.Run("OK - Suffix wins over prefix", func(t *testing.T) {
+		assert.Equal(t, cloudwatch.StandardUnitBytes, b.getMetricUnit("http.resp.bytes", cloudwatch.StandardUnitCount))
+	})
+
+	t.Run("OK - Prefix wins over regexp", func(t *testing.T) {
+		assert.Equal(t, cloudwatch.StandardUnitSeconds, b.getMetricUnit("http.latency", cloudwatch.StandardUnitCount))
+	})
+
+	t.Run("OK - Default unit", func(t *testing.T) {
+		assert.Equal(t, cloudwatch.StandardUnitCount, b.getMetricUnit("db.latency", cloudwatch.StandardUnitCount))
+	})
+}
+
+func TestValidateUnits(t *testing.T) {
+	t.Run("OK - Valid units", func(t *testing.T) {
+		err := ValidateUnits(
+			map[string]string{"a": cloudwatch.StandardUnitBytes},
+			[]UnitRule{SuffixUnit(".ms", cloudwatch.StandardUnitMilliseconds)},
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("NOK - Invalid units", func(t *testing.T) {
+		err := ValidateUnits(
+			map[string]string{"b": "bytes", "a": cloudwatch.StandardUnitBytes, "c": "Secs"},
+			[]UnitRule{PrefixUnit("lat.", "Milisecond")},
+		)
+		require.Error(t, err)
+		assert.EqualError(t, err,
+			`invalid unit(s): "Secs" for metric "c", "bytes" for metric "b", "Milisecond" for prefix "lat."`)
+	})
+}
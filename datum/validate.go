@@ -0,0 +1,160 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// Limits of the datums accepted by CloudWatch. A single datum out of them fails its whole
+// PutMetricData request
+const (
+	// MaxNameLength is the maximum length of metric names, dimension names and dimension values
+	MaxNameLength = 255
+	// MaxValue is the largest magnitude of a value
+	MaxValue float64 = 1 << 360
+)
+
+// InvalidPolicy decides what happens to the datums CloudWatch would reject
+type InvalidPolicy int
+
+const (
+	// DropInvalid drops invalid datums
+	DropInvalid InvalidPolicy = iota
+	// ClampInvalid fixes invalid datums where possible, see Clamp, and drops the others
+	ClampInvalid
+)
+
+// Validate returns the reason why CloudWatch would reject d, or nil when d is valid
+func Validate(d *cloudwatch.MetricDatum) error {
+	if n := len(aws.StringValue(d.MetricName)); n == 0 || n > MaxNameLength {
+		return fmt.Errorf("metric name length %d is out of [1, %d]", n, MaxNameLength)
+	}
+
+	for _, dim := range d.Dimensions {
+		name, value := aws.StringValue(dim.Name), aws.StringValue(dim.Value)
+		if name == "" || len(name) > MaxNameLength {
+			return fmt.Errorf("dimension name length %d is out of [1, %d]", len(name), MaxNameLength)
+		}
+		if value == "" {
+			return fmt.Errorf("empty value for dimension %s", name)
+		}
+		if len(value) > MaxNameLength {
+			return fmt.Errorf("value of dimension %s is longer than %d", name, MaxNameLength)
+		}
+	}
+
+	if d.Value != nil {
+		if err := validateValue(*d.Value); err != nil {
+			return err
+		}
+	}
+	for _, v := range d.Values {
+		if err := validateValue(aws.Float64Value(v)); err != nil {
+			return err
+		}
+	}
+	if sv := d.StatisticValues; sv != nil {
+		for _, v := range []*float64{sv.SampleCount, sv.Sum, sv.Minimum, sv.Maximum} {
+			if err := validateValue(aws.Float64Value(v)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateValue(v float64) error {
+	switch {
+	case math.IsNaN(v):
+		return errors.New("value is NaN")
+	case math.IsInf(v, 0):
+		return fmt.Errorf("value is %v", v)
+	case math.Abs(v) > MaxValue:
+		return fmt.Errorf("value %g is out of ±2^360", v)
+	}
+	return nil
+}
+
+// Clamp fixes d in place where possible: infinite and out of range values are clamped to
+// ±MaxValue, and long names and dimension values are truncated. It returns the reason why d is
+// still invalid, e.g. a NaN value or an empty dimension value, or nil. Values and dimensions are
+// replaced rather than modified, since they can be shared with other datums
+func Clamp(d *cloudwatch.MetricDatum) error {
+	if d.MetricName != nil {
+		d.MetricName = aws.String(truncate(*d.MetricName, MaxNameLength))
+	}
+
+	dims := make([]*cloudwatch.Dimension, 0, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		dims = append(dims, &cloudwatch.Dimension{
+			Name:  aws.String(truncate(aws.StringValue(dim.Name), MaxNameLength)),
+			Value: aws.String(truncate(aws.StringValue(dim.Value), MaxNameLength)),
+		})
+	}
+	if d.Dimensions != nil {
+		d.Dimensions = dims
+	}
+
+	if d.Value != nil {
+		d.Value = aws.Float64(clampValue(*d.Value))
+	}
+	if d.Values != nil {
+		values := make([]*float64, 0, len(d.Values))
+		for _, v := range d.Values {
+			values = append(values, aws.Float64(clampValue(aws.Float64Value(v))))
+		}
+		d.Values = values
+	}
+	if sv := d.StatisticValues; sv != nil {
+		d.StatisticValues = &cloudwatch.StatisticSet{
+			SampleCount: aws.Float64(clampValue(aws.Float64Value(sv.SampleCount))),
+			Sum:         aws.Float64(clampValue(aws.Float64Value(sv.Sum))),
+			Minimum:     aws.Float64(clampValue(aws.Float64Value(sv.Minimum))),
+			Maximum:     aws.Float64(clampValue(aws.Float64Value(sv.Maximum))),
+		}
+	}
+
+	return Validate(d)
+}
+
+// clampValue brings v back into ±MaxValue, NaN is left as is
+func clampValue(v float64) float64 {
+	switch {
+	case v > MaxValue:
+		return MaxValue
+	case v < -MaxValue:
+		return -MaxValue
+	}
+	return v
+}
+
+// truncate cuts s to at most n bytes without splitting a multi-byte character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
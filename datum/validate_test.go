@@ -0,0 +1,103 @@
+package datum
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *cloudwatch.MetricDatum {
+		return &cloudwatch.MetricDatum{
+			MetricName: aws.String("requests"),
+			Value:      aws.Float64(1),
+			Dimensions: []*cloudwatch.Dimension{{Name: aws.String("env"), Value: aws.String("prod")}},
+		}
+	}
+
+	t.Run("OK - Valid", func(t *testing.T) {
+		assert.NoError(t, Validate(valid()))
+
+		d := valid()
+		d.Value = aws.Float64(-MaxValue)
+		assert.NoError(t, Validate(d))
+	})
+
+	tests := []struct {
+		name   string
+		modify func(d *cloudwatch.MetricDatum)
+		err    string
+	}{
+		{"NaN", func(d *cloudwatch.MetricDatum) { d.Value = aws.Float64(math.NaN()) }, "value is NaN"},
+		{"Inf", func(d *cloudwatch.MetricDatum) { d.Value = aws.Float64(math.Inf(-1)) }, "value is -Inf"},
+		{"Out of range", func(d *cloudwatch.MetricDatum) { d.Value = aws.Float64(MaxValue * 2) }, "value 4.6970851655476665e+108 is out of ±2^360"},
+		{"Statistic values", func(d *cloudwatch.MetricDatum) {
+			d.Value = nil
+			d.StatisticValues = &cloudwatch.StatisticSet{
+				SampleCount: aws.Float64(1), Sum: aws.Float64(math.NaN()), Minimum: aws.Float64(0), Maximum: aws.Float64(0),
+			}
+		}, "value is NaN"},
+		{"Empty name", func(d *cloudwatch.MetricDatum) { d.MetricName = aws.String("") }, "metric name length 0 is out of [1, 255]"},
+		{"Long name", func(d *cloudwatch.MetricDatum) { d.MetricName = aws.String(strings.Repeat("a", 256)) }, "metric name length 256 is out of [1, 255]"},
+		{"Empty dimension value", func(d *cloudwatch.MetricDatum) { d.Dimensions[0].Value = aws.String("") }, "empty value for dimension env"},
+		{"Long dimension value", func(d *cloudwatch.MetricDatum) { d.Dimensions[0].Value = aws.String(strings.Repeat("a", 256)) }, "value of dimension env is longer than 255"},
+	}
+	for _, tt := range tests {
+		t.Run("NOK - "+tt.name, func(t *testing.T) {
+			d := valid()
+			tt.modify(d)
+			assert.EqualError(t, Validate(d), tt.err)
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	t.Run("OK - Values and names", func(t *testing.T) {
+		shared := &cloudwatch.Dimension{Name: aws.String("tenant"), Value: aws.String(strings.Repeat("é", 200))}
+		d := &cloudwatch.MetricDatum{
+			MetricName: aws.String(strings.Repeat("a", 300)),
+			Value:      aws.Float64(math.Inf(1)),
+			Values:     []*float64{aws.Float64(-MaxValue * 2), aws.Float64(1)},
+			Dimensions: []*cloudwatch.Dimension{shared},
+		}
+
+		require.NoError(t, Clamp(d))
+		assert.Len(t, *d.MetricName, MaxNameLength)
+		assert.Equal(t, MaxValue, *d.Value)
+		assert.Equal(t, []*float64{aws.Float64(-MaxValue), aws.Float64(1)}, d.Values)
+		assert.Equal(t, strings.Repeat("é", 127), *d.Dimensions[0].Value)
+		// Shared dimensions are left untouched
+		assert.Len(t, *shared.Value, 400)
+	})
+
+	t.Run("NOK - Cannot be fixed", func(t *testing.T) {
+		d := &cloudwatch.MetricDatum{MetricName: aws.String("m"), Value: aws.Float64(math.NaN())}
+		assert.EqualError(t, Clamp(d), "value is NaN")
+
+		d = &cloudwatch.MetricDatum{
+			MetricName: aws.String("m"),
+			Value:      aws.Float64(1),
+			Dimensions: []*cloudwatch.Dimension{{Name: aws.String("env"), Value: aws.String("")}},
+		}
+		assert.EqualError(t, Clamp(d), "empty value for dimension env")
+	})
+}
@@ -0,0 +1,168 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// DeadLetter holds datums that could not be delivered. Attempts is the number of PutMetricData
+// calls made with them, 0 when they were not sent, e.g. invalid or quarantined datums
+type DeadLetter struct {
+	Namespace string
+	Datums    []*cloudwatch.MetricDatum
+	Err       error
+	Attempts  int
+}
+
+// DeadLetterHandler receives the datums that could not be delivered, to audit or replay them
+type DeadLetterHandler interface {
+	Handle(l DeadLetter) error
+}
+
+// DeadLetterFunc is a function implementing DeadLetterHandler
+type DeadLetterFunc func(l DeadLetter) error
+
+// Handle calls f(l)
+func (f DeadLetterFunc) Handle(l DeadLetter) error {
+	return f(l)
+}
+
+// deadLetterRecord is the NDJSON representation of an undelivered datum, the datum can be
+// decoded back into a cloudwatch.MetricDatum
+type deadLetterRecord struct {
+	Namespace string                  `json:"namespace"`
+	Error     string                  `json:"error"`
+	Attempts  int                     `json:"attempts"`
+	Datum     *cloudwatch.MetricDatum `json:"datum"`
+}
+
+func deadLetterRecords(l DeadLetter) []deadLetterRecord {
+	errMsg := ""
+	if l.Err != nil {
+		errMsg = l.Err.Error()
+	}
+
+	res := make([]deadLetterRecord, 0, len(l.Datums))
+	for _, d := range l.Datums {
+		res = append(res, deadLetterRecord{Namespace: l.Namespace, Error: errMsg, Attempts: l.Attempts, Datum: d})
+	}
+	return res
+}
+
+// NDJSONDeadLetter writes undelivered datums to a writer, one JSON object per line
+type NDJSONDeadLetter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewNDJSONDeadLetter creates a NDJSONDeadLetter writing to w
+func NewNDJSONDeadLetter(w io.Writer) *NDJSONDeadLetter {
+	return &NDJSONDeadLetter{enc: json.NewEncoder(w)}
+}
+
+// OpenDeadLetterFile creates a NDJSONDeadLetter appending to the file at path, which is created
+// when missing. Close closes the file
+func OpenDeadLetterFile(path string) (*NDJSONDeadLetter, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	h := NewNDJSONDeadLetter(f)
+	h.c = f
+	return h, nil
+}
+
+// Handle writes a line per datum of l
+func (h *NDJSONDeadLetter) Handle(l DeadLetter) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range deadLetterRecords(l) {
+		if err := h.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying file, if any
+func (h *NDJSONDeadLetter) Close() error {
+	if h.c == nil {
+		return nil
+	}
+	return h.c.Close()
+}
+
+// NewLogDeadLetter creates a DeadLetterHandler logging every undelivered datum as an error
+func NewLogDeadLetter(logger Logger) DeadLetterHandler {
+	return DeadLetterFunc(func(l DeadLetter) error {
+		for _, r := range deadLetterRecords(l) {
+			b, err := json.Marshal(r.Datum)
+			if err != nil {
+				return err
+			}
+
+			logger.WithField("namespace", r.Namespace).
+				WithField("metric", aws.StringValue(r.Datum.MetricName)).
+				WithField("attempts", r.Attempts).
+				WithError(l.Err).
+				Errorf("undelivered datum: %s", b)
+		}
+		return nil
+	})
+}
+
+// undelivered are datums that could not be delivered after attempts calls
+type undelivered struct {
+	datums   []*cloudwatch.MetricDatum
+	attempts int
+}
+
+// undeliverable hands the datums that could not be delivered to the dead-letter handler, and
+// returns how many they are
+func (p *publisher) undeliverable(namespace string, failed []undelivered, err error) int {
+	n := 0
+	for _, u := range failed {
+		n += len(u.datums)
+		p.deadLetter(namespace, u.datums, err, u.attempts)
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		p.self.breakerDenied.Inc(int64(n))
+		return n
+	}
+	p.self.dropped(n)
+	return n
+}
+
+func (p *publisher) deadLetter(namespace string, datums []*cloudwatch.MetricDatum, err error, attempts int) {
+	if p.deadLetters == nil || len(datums) == 0 {
+		return
+	}
+
+	l := DeadLetter{Namespace: namespace, Datums: datums, Err: err, Attempts: attempts}
+	if herr := p.deadLetters.Handle(l); herr != nil {
+		p.logger.WithError(herr).Errorf("dead-letter handler failed, %d datum(s) lost", len(datums))
+	}
+}
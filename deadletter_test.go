@@ -0,0 +1,199 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestDeadLetterHandlers(t *testing.T) {
+	l := DeadLetter{
+		Namespace: "nmsp",
+		Datums: []*cloudwatch.MetricDatum{
+			{MetricName: aws.String("a"), Value: aws.Float64(1)},
+			{MetricName: aws.String("b"), Value: aws.Float64(2)},
+		},
+		Err:      errors.New("something happened"),
+		Attempts: 2,
+	}
+
+	decode := func(t *testing.T, b []byte) []deadLetterRecord {
+		res := []deadLetterRecord{}
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		for scanner.Scan() {
+			r := deadLetterRecord{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+			res = append(res, r)
+		}
+		return res
+	}
+
+	t.Run("OK - NDJSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, NewNDJSONDeadLetter(buf).Handle(l))
+
+		records := decode(t, buf.Bytes())
+		require.Len(t, records, 2)
+		assert.Equal(t, deadLetterRecord{
+			Namespace: "nmsp",
+			Error:     "something happened",
+			Attempts:  2,
+			Datum:     l.Datums[1],
+		}, records[1])
+	})
+
+	t.Run("OK - File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+		for i := 0; i < 2; i++ {
+			h, err := OpenDeadLetterFile(path)
+			require.NoError(t, err)
+			require.NoError(t, h.Handle(l))
+			require.NoError(t, h.Close())
+		}
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, decode(t, b), 4)
+	})
+
+	t.Run("NOK - File cannot be opened", func(t *testing.T) {
+		_, err := OpenDeadLetterFile(filepath.Join(t.TempDir(), "missing", "dead-letters.ndjson"))
+		assert.Error(t, err)
+	})
+
+	t.Run("OK - Log", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		require.NoError(t, NewLogDeadLetter(NewLogrusLogger(logger)).Handle(l))
+
+		require.Len(t, hook.Entries, 2)
+		entry := hook.Entries[0]
+		assert.Equal(t, logrus.ErrorLevel, entry.Level)
+		assert.Contains(t, entry.Message, `undelivered datum: {`)
+		assert.Contains(t, entry.Message, `"MetricName":"a"`)
+		assert.Equal(t, "nmsp", entry.Data["namespace"])
+		assert.Equal(t, "a", entry.Data["metric"])
+		assert.Equal(t, 2, entry.Data["attempts"])
+		assert.Equal(t, l.Err, entry.Data[logrus.ErrorKey])
+	})
+}
+
+func TestPublisher__DeadLetter(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	data := []*cloudwatch.MetricDatum{
+		{MetricName: aws.String("a"), Value: aws.Float64(1)},
+		{MetricName: aws.String("bad"), Value: aws.Float64(2)},
+	}
+	logger, hook := test.NewNullLogger()
+
+	newPublisher := func(cw CloudWatch, h DeadLetterHandler) *publisher {
+		return NewPublisher(metrics.NewRegistry(), "nmsp",
+			WithClient(cw),
+			WithLogger(logger),
+			WithDeadLetter(h),
+		).(*publisher)
+	}
+
+	t.Run("OK - Failed batch", func(t *testing.T) {
+		err := errors.New("something happened")
+		cw := mock.NewCloudWatchMock(mc)
+		cw.PutMetricDataMock.Return(nil, err)
+
+		letters := []DeadLetter{}
+		p := newPublisher(cw, DeadLetterFunc(func(l DeadLetter) error {
+			letters = append(letters, l)
+			return nil
+		}))
+		p.publishMetrics(namespacedData{"nmsp": data})
+
+		assert.Equal(t, []DeadLetter{{Namespace: "nmsp", Datums: data, Err: err, Attempts: 1}}, letters)
+	})
+
+	t.Run("OK - Bisected and quarantined datums", func(t *testing.T) {
+		rejected := awserr.New(cloudwatch.ErrCodeInvalidParameterValueException, "bad datum", nil)
+		cw := mock.NewCloudWatchMock(mc)
+		cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+			for _, d := range input.MetricData {
+				if *d.MetricName == "bad" {
+					return nil, rejected
+				}
+			}
+			return nil, nil
+		})
+
+		letters := []DeadLetter{}
+		p := newPublisher(cw, DeadLetterFunc(func(l DeadLetter) error {
+			letters = append(letters, l)
+			return nil
+		}))
+		p.publishMetrics(namespacedData{"nmsp": data})
+		p.publishMetrics(namespacedData{"nmsp": data})
+
+		assert.Equal(t, []DeadLetter{
+			{Namespace: "nmsp", Datums: data[1:], Err: rejected, Attempts: 2},
+			{Namespace: "nmsp", Datums: data[1:], Err: ErrQuarantined},
+		}, letters)
+	})
+
+	t.Run("OK - Invalid datums", func(t *testing.T) {
+		letters := []DeadLetter{}
+		p := newPublisher(mock.NewCloudWatchMock(mc), DeadLetterFunc(func(l DeadLetter) error {
+			letters = append(letters, l)
+			return nil
+		}))
+
+		nan := &cloudwatch.MetricDatum{MetricName: aws.String("nan"), Value: aws.Float64(math.NaN())}
+		p.sanitize(namespacedData{"nmsp": {nan}})
+
+		require.Len(t, letters, 1)
+		assert.Equal(t, []*cloudwatch.MetricDatum{nan}, letters[0].Datums)
+		assert.EqualError(t, letters[0].Err, "value is NaN")
+		assert.Zero(t, letters[0].Attempts)
+	})
+
+	t.Run("NOK - Handler error is logged", func(t *testing.T) {
+		hook.Reset()
+		cw := mock.NewCloudWatchMock(mc)
+		cw.PutMetricDataMock.Return(nil, errors.New("something happened"))
+
+		p := newPublisher(cw, DeadLetterFunc(func(l DeadLetter) error {
+			return errors.New("disk full")
+		}))
+		p.publishMetrics(namespacedData{"nmsp": data})
+
+		require.Len(t, hook.Entries, 2)
+		assert.Equal(t, "dead-letter handler failed, 2 datum(s) lost", hook.Entries[1].Message)
+		assert.EqualError(t, hook.Entries[1].Data[logrus.ErrorKey].(error), "disk full")
+	})
+}
@@ -0,0 +1,226 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/go-metrics"
+)
+
+// Snapshot gives the values of the metrics of a registry at poll time
+type Snapshot interface {
+	Value(name string) (float64, bool)
+}
+
+// Expr computes the value of a derived metric from a snapshot. It returns false when the value
+// cannot be computed, e.g. a missing metric or a division by zero
+type Expr func(s Snapshot) (float64, bool)
+
+// Derived is a metric computed at poll time from the other metrics of a registry, and published
+// along with them. Unit is looked up as for the other metrics when empty, and defaults to None
+type Derived struct {
+	Name string
+	Unit string
+	Expr Expr
+}
+
+// Metric is the value of a registered metric: the increase since the previous poll for counters,
+// meters, histograms and timers (their count), the value for gauges and the rate for EWMAs
+func Metric(name string) Expr {
+	return func(s Snapshot) (float64, bool) {
+		return s.Value(name)
+	}
+}
+
+// Sum adds up exprs
+func Sum(exprs ...Expr) Expr {
+	return func(s Snapshot) (float64, bool) {
+		sum := 0.0
+		for _, e := range exprs {
+			v, ok := e(s)
+			if !ok {
+				return 0, false
+			}
+			sum += v
+		}
+		return sum, true
+	}
+}
+
+// Ratio divides numerator by denominator, it is not computed when denominator is 0
+func Ratio(numerator, denominator Expr) Expr {
+	return func(s Snapshot) (float64, bool) {
+		n, ok := numerator(s)
+		if !ok {
+			return 0, false
+		}
+		d, ok := denominator(s)
+		if !ok || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+}
+
+// Scale multiplies e by factor, e.g. 100 to turn a ratio into a percentage
+func Scale(e Expr, factor float64) Expr {
+	return func(s Snapshot) (float64, bool) {
+		v, ok := e(s)
+		return v * factor, ok
+	}
+}
+
+// snapshot holds the values of the metrics of a source for one poll
+type snapshot map[string]float64
+
+func (s snapshot) Value(name string) (float64, bool) {
+	v, ok := s[name]
+	return v, ok
+}
+
+// lastCount is the count of a metric at the last poll it was seen
+type lastCount struct {
+	count float64
+	poll  uint64
+}
+
+// deriver evaluates the derived metrics on the snapshot of each source. Counts are turned into
+// deltas with the counts of the previous poll, unless cleared on flush, which makes them local to
+// the interval already
+type deriver struct {
+	derived     []Derived
+	perInterval bool
+	counts      map[metricKey]*lastCount
+	poll        uint64
+	current     snapshot
+}
+
+func newDeriver(derived []Derived, perInterval bool) *deriver {
+	return &deriver{
+		derived:     derived,
+		perInterval: perInterval,
+		counts:      map[metricKey]*lastCount{},
+	}
+}
+
+// begin starts a new interval
+func (d *deriver) begin() {
+	d.poll++
+}
+
+// beginSource starts the snapshot of a source
+func (d *deriver) beginSource() {
+	if len(d.derived) > 0 {
+		d.current = snapshot{}
+	}
+}
+
+// observe records the value of a metric in the snapshot of its source
+func (d *deriver) observe(src *source, name string, i interface{}) {
+	if len(d.derived) == 0 {
+		return
+	}
+
+	switch v := i.(type) {
+	case metrics.Counter:
+		d.current[name] = d.delta(src, name, float64(v.Count()))
+	case metrics.Gauge:
+		d.current[name] = float64(v.Value())
+	case metrics.GaugeFloat64:
+		d.current[name] = v.Value()
+	case metrics.Histogram:
+		d.current[name] = d.count(src, name, v, float64(v.Count()))
+	case metrics.Meter:
+		d.current[name] = d.delta(src, name, float64(v.Count()))
+	case metrics.Timer:
+		d.current[name] = d.count(src, name, v, float64(v.Count()))
+	case metrics.EWMA:
+		d.current[name] = v.Rate()
+	}
+}
+
+// count returns the count of a histogram or timer for the interval
+func (d *deriver) count(src *source, name string, i interface{}, count float64) float64 {
+	if d.perInterval && clearedOnFlush(i) {
+		return count
+	}
+	return d.delta(src, name, count)
+}
+
+// delta returns the increase of a count since the previous poll. The first poll counts from 0,
+// and a count lower than the previous one was reset
+func (d *deriver) delta(src *source, name string, count float64) float64 {
+	key := metricKey{src: src, name: name}
+	last, ok := d.counts[key]
+	if !ok {
+		last = &lastCount{}
+		d.counts[key] = last
+	}
+
+	prev := last.count
+	last.count = count
+	last.poll = d.poll
+	if count < prev {
+		return count
+	}
+	return count - prev
+}
+
+// sweep forgets the counts of the metrics which were not polled during the interval
+func (d *deriver) sweep() {
+	for key, last := range d.counts {
+		if last.poll != d.poll {
+			delete(d.counts, key)
+		}
+	}
+}
+
+// derivedData holds the datums built for a derived metric, with its name before transformation
+type derivedData struct {
+	name string
+	data []*cloudwatch.MetricDatum
+}
+
+// buildDerived builds the datums of the derived metrics computable on the snapshot of the
+// current source
+func (p *publisher) buildDerived(t time.Time) []derivedData {
+	res := []derivedData{}
+	for _, m := range p.deriver.derived {
+		v, ok := m.Expr(p.deriver.current)
+		if !ok {
+			continue
+		}
+
+		if p.valueBuilder != nil {
+			res = append(res, derivedData{name: m.Name, data: p.valueBuilder.BuildValueDataAt(m.Name, v, m.Unit, t)})
+			continue
+		}
+
+		unit := m.Unit
+		if unit == "" {
+			unit = cloudwatch.StandardUnitNone
+		}
+		res = append(res, derivedData{name: m.Name, data: []*cloudwatch.MetricDatum{{
+			MetricName: aws.String(m.Name),
+			Value:      aws.Float64(v),
+			Unit:       aws.String(unit),
+			Timestamp:  aws.Time(t.UTC()),
+		}}})
+	}
+	return res
+}
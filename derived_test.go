@@ -0,0 +1,163 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestExpr(t *testing.T) {
+	s := snapshot{"errors": 5, "requests": 20, "zero": 0}
+
+	tests := []struct {
+		name string
+		expr Expr
+		v    float64
+		ok   bool
+	}{
+		{"OK - Metric", Metric("errors"), 5, true},
+		{"OK - Sum", Sum(Metric("errors"), Metric("requests")), 25, true},
+		{"OK - Ratio", Ratio(Metric("errors"), Metric("requests")), .25, true},
+		{"OK - Percentage", Scale(Ratio(Metric("errors"), Metric("requests")), 100), 25, true},
+		{"NOK - Missing metric", Metric("unknown"), 0, false},
+		{"NOK - Missing term", Sum(Metric("errors"), Metric("unknown")), 0, false},
+		{"NOK - Division by zero", Ratio(Metric("errors"), Metric("zero")), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := tt.expr(s)
+			assert.Equal(t, tt.ok, ok)
+			if ok {
+				assert.Equal(t, tt.v, v)
+			}
+		})
+	}
+}
+
+func TestPublisher__Derived(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	errs := metrics.NewCounter()
+	requests := metrics.NewCounter()
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("errors", errs))
+	require.NoError(t, registry.Register("requests", requests))
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithDerived(Derived{
+			Name: "errors.rate",
+			Unit: cloudwatch.StandardUnitPercent,
+			Expr: Scale(Ratio(Metric("errors"), Metric("requests")), 100),
+		}),
+	).(*publisher)
+
+	derived := func(ts time.Time) *cloudwatch.MetricDatum {
+		for _, d := range flatten(p.pollOnce(ts)) {
+			if *d.MetricName == "errors.rate" {
+				return d
+			}
+		}
+		return nil
+	}
+	ts := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("NOK - Not computed without requests", func(t *testing.T) {
+		assert.Nil(t, derived(ts))
+	})
+
+	t.Run("OK - Ratio of the increases since the previous poll", func(t *testing.T) {
+		errs.Inc(10)
+		requests.Inc(100)
+		assert.Equal(t, &cloudwatch.MetricDatum{
+			MetricName:        aws.String("errors.rate"),
+			Value:             aws.Float64(10),
+			Unit:              aws.String(cloudwatch.StandardUnitPercent),
+			StorageResolution: aws.Int64(60),
+			Timestamp:         aws.Time(ts),
+		}, derived(ts))
+
+		errs.Inc(1)
+		requests.Inc(4)
+		assert.Equal(t, 25.0, *derived(ts).Value)
+	})
+
+	t.Run("OK - Counters reset", func(t *testing.T) {
+		errs.Clear()
+		requests.Clear()
+		errs.Inc(1)
+		requests.Inc(2)
+		assert.Equal(t, 50.0, *derived(ts).Value)
+	})
+
+	t.Run("OK - Counts of removed metrics are forgotten", func(t *testing.T) {
+		require.Len(t, p.deriver.counts, 2)
+		registry.Unregister("errors")
+		assert.Nil(t, derived(ts))
+		require.Len(t, p.deriver.counts, 1)
+		assert.Contains(t, p.deriver.counts, metricKey{src: p.sources[0], name: "requests"})
+	})
+}
+
+func TestSettings__Derived(t *testing.T) {
+	s := getSettings([]Option{
+		WithDerived(
+			Derived{Name: "valid", Expr: Metric("a")},
+			Derived{Name: "no-expr"},
+			Derived{Name: "bad-unit", Unit: "percent", Expr: Metric("a")},
+		),
+	})
+	assert.Error(t, s.validate())
+
+	s.dropInvalid()
+	require.Len(t, s.Derived, 1)
+	assert.Equal(t, "valid", s.Derived[0].Name)
+	assert.NoError(t, s.validate())
+}
+
+func TestPublisher__DerivedRouting(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("http.errors", registry).Inc(1)
+	metrics.GetOrRegisterCounter("http.requests", registry).Inc(4)
+
+	p := NewPublisher(registry, "service",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithNameSanitizer(regexp.MustCompile(`\.`), "_"),
+		WithNamespaceRules(PrefixNamespace("http.", "Platform/HTTP")),
+		WithDerived(Derived{Name: "http.error_ratio", Expr: Ratio(Metric("http.errors"), Metric("http.requests"))}),
+	).(*publisher)
+
+	data := p.pollOnce(time.Time{})
+	assert.Equal(t, []string{"Platform/HTTP"}, data.namespaces())
+	names := []string{}
+	for _, d := range data["Platform/HTTP"] {
+		names = append(names, *d.MetricName)
+	}
+	assert.ElementsMatch(t, []string{"http_errors", "http_requests", "http_error_ratio"}, names)
+}
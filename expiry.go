@@ -0,0 +1,131 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"time"
+
+	"github.com/weareyolo/go-metrics"
+)
+
+// expiryState is what is known of the activity of a metric
+type expiryState struct {
+	value   float64
+	changed time.Time
+	idle    int
+	expired bool
+	poll    uint64
+}
+
+// expirer tracks when metrics last changed, to stop publishing the ones idle for too long.
+// Metrics cleared on flush are active whenever their count is not zero
+type expirer struct {
+	intervals   int
+	perInterval bool
+	state       map[metricKey]*expiryState
+	poll        uint64
+}
+
+func newExpirer(intervals int, perInterval bool) *expirer {
+	return &expirer{
+		intervals:   intervals,
+		perInterval: perInterval,
+		state:       map[metricKey]*expiryState{},
+	}
+}
+
+// activity returns a value changing whenever the metric is updated. Metrics whose activity is
+// unknown never expire
+func activity(i interface{}) (float64, bool) {
+	switch v := i.(type) {
+	case metrics.Counter:
+		return float64(v.Count()), true
+	case metrics.Gauge:
+		return float64(v.Value()), true
+	case metrics.GaugeFloat64:
+		return v.Value(), true
+	case metrics.Histogram:
+		return float64(v.Count()), true
+	case metrics.Meter:
+		return float64(v.Count()), true
+	case metrics.Timer:
+		return float64(v.Count()), true
+	default:
+		return 0, false
+	}
+}
+
+// begin starts a new interval
+func (e *expirer) begin() {
+	e.poll++
+}
+
+// observe records the activity of a metric for the current interval. It reports whether the
+// metric is expired, and whether it expired during this interval
+func (e *expirer) observe(src *source, name string, i interface{}, now time.Time) (expired, justExpired bool) {
+	if e.intervals <= 0 {
+		return false, false
+	}
+	value, ok := activity(i)
+	if !ok {
+		return false, false
+	}
+
+	key := metricKey{src: src, name: name}
+	st, ok := e.state[key]
+	changed := !ok || st.value != value
+	if e.perInterval && clearedOnFlush(i) {
+		changed = !ok || value > 0
+	}
+	if changed {
+		e.state[key] = &expiryState{value: value, changed: now, poll: e.poll}
+		return false, false
+	}
+
+	st.poll = e.poll
+	st.idle++
+	if st.expired {
+		return true, false
+	}
+	st.expired = st.idle >= e.intervals
+	return st.expired, st.expired
+}
+
+// observeExpiry records the activity of a metric of src. The self metrics never expire: the
+// publisher keeps updating them, unregistered or not
+func (p *publisher) observeExpiry(src *source, name string, i interface{}, now time.Time) (expired, justExpired bool) {
+	if src.Registry == p.self.registry {
+		return false, false
+	}
+	return p.expirer.observe(src, name, i, now)
+}
+
+// lastChanged returns when the metric last changed
+func (e *expirer) lastChanged(src *source, name string) time.Time {
+	if st, ok := e.state[metricKey{src: src, name: name}]; ok {
+		return st.changed
+	}
+	return time.Time{}
+}
+
+// sweep drops the state of the metrics not seen during the current interval, e.g. unregistered
+// by the application
+func (e *expirer) sweep() {
+	for key, st := range e.state {
+		if st.poll != e.poll {
+			delete(e.state, key)
+		}
+	}
+}
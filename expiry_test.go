@@ -0,0 +1,178 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestExpirer__Observe(t *testing.T) {
+	src := newSource(Source{Namespace: "nmsp"}, nil)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("OK - Disabled", func(t *testing.T) {
+		e := newExpirer(0, false)
+		for i := 0; i < 3; i++ {
+			e.begin()
+			expired, _ := e.observe(src, "c", metrics.NewCounter(), now)
+			assert.False(t, expired)
+		}
+	})
+
+	t.Run("OK - Unknown activity never expires", func(t *testing.T) {
+		e := newExpirer(1, false)
+		for i := 0; i < 3; i++ {
+			e.begin()
+			expired, _ := e.observe(src, "h", metrics.NewHealthcheck(nil), now)
+			assert.False(t, expired)
+		}
+	})
+
+	t.Run("OK - Expires then comes back on change", func(t *testing.T) {
+		e := newExpirer(2, false)
+		c := metrics.NewCounter()
+		observe := func(i int) (bool, bool) {
+			e.begin()
+			return e.observe(src, "c", c, now.Add(time.Duration(i)*time.Minute))
+		}
+
+		expired, just := observe(0)
+		assert.False(t, expired || just)
+		expired, just = observe(1)
+		assert.False(t, expired || just)
+		expired, just = observe(2)
+		assert.True(t, expired)
+		assert.True(t, just)
+		expired, just = observe(3)
+		assert.True(t, expired)
+		assert.False(t, just)
+		assert.Equal(t, now, e.lastChanged(src, "c"))
+
+		c.Inc(1)
+		expired, just = observe(4)
+		assert.False(t, expired || just)
+		assert.Equal(t, now.Add(4*time.Minute), e.lastChanged(src, "c"))
+	})
+
+	t.Run("OK - Unseen metrics are forgotten", func(t *testing.T) {
+		e := newExpirer(2, false)
+		e.begin()
+		e.observe(src, "c", metrics.NewCounter(), now)
+		e.sweep()
+		assert.Len(t, e.state, 1)
+
+		e.begin()
+		e.sweep()
+		assert.Empty(t, e.state)
+	})
+}
+
+func TestPublisher__Expiry(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	names := func(data namespacedData) []string {
+		res := []string{}
+		for _, d := range flatten(data) {
+			res = append(res, *d.MetricName)
+		}
+		return res
+	}
+
+	t.Run("OK - Expired metrics are not published", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		active := metrics.GetOrRegisterCounter("active", registry)
+		metrics.GetOrRegisterCounter("tenant.1", registry)
+
+		expired := []ExpiredMetric{}
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithExpiry(2, false),
+			OnMetricExpired(func(m ExpiredMetric) { expired = append(expired, m) }),
+		).(*publisher)
+
+		// tenant.1 is idle from the second interval on, and expires during the third one
+		for i := 0; i < 2; i++ {
+			active.Inc(1)
+			assert.ElementsMatch(t, []string{"active", "tenant.1"}, names(p.pollOnce(now.Add(time.Duration(i)*time.Minute))))
+		}
+		assert.Empty(t, expired)
+
+		active.Inc(1)
+		assert.Equal(t, []string{"active"}, names(p.pollOnce(now.Add(2*time.Minute))))
+		require.Len(t, expired, 1)
+		assert.Equal(t, ExpiredMetric{
+			Registry:    registry,
+			Namespace:   "nmsp",
+			Name:        "tenant.1",
+			LastChanged: now,
+		}, expired[0])
+		assert.NotNil(t, registry.Get("tenant.1"))
+	})
+
+	t.Run("OK - Expired metrics are unregistered", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		metrics.GetOrRegisterGauge("queue.depth", registry)
+
+		expired := []ExpiredMetric{}
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithExpiry(1, true),
+			OnMetricExpired(func(m ExpiredMetric) { expired = append(expired, m) }),
+		).(*publisher)
+
+		assert.Len(t, names(p.pollOnce(now)), 1)
+		assert.Empty(t, names(p.pollOnce(now.Add(time.Minute))))
+		require.Len(t, expired, 1)
+		assert.True(t, expired[0].Unregistered)
+		assert.Nil(t, registry.Get("queue.depth"))
+	})
+}
+
+func TestPublisher__ExpirySelfMetrics(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	selfRegistry := metrics.NewRegistry()
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithSelfMetrics(selfRegistry),
+		WithSelfMetricsPublished(),
+		WithExpiry(1, true),
+	).(*publisher)
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		p.pollOnce(now.Add(time.Duration(i) * time.Minute))
+	}
+
+	// Idle self metrics stay registered and published
+	assert.NotNil(t, selfRegistry.Get(SelfMetricsPrefix+"batches.failed"))
+	p.self.batchFailed()
+	names := []string{}
+	for _, d := range flatten(p.pollOnce(now.Add(3 * time.Minute))) {
+		names = append(names, *d.MetricName)
+	}
+	assert.Contains(t, names, SelfMetricsPrefix+"batches.failed")
+	assert.Contains(t, names, SelfMetricsPrefix+"datums.dropped")
+}
@@ -0,0 +1,89 @@
+package health
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Status describes the health of the metric delivery
+type Status struct {
+	LastAttempt         time.Time
+	LastSuccess         time.Time
+	ConsecutiveFailures int
+	LastError           error
+	QueueDepth          int
+	Interval            time.Duration
+}
+
+// Reporter is implemented by anything able to report a Status, such as cloudmetrics.Publisher
+type Reporter interface {
+	Status() Status
+}
+
+type statusJSON struct {
+	Healthy             bool       `json:"healthy"`
+	LastAttempt         *time.Time `json:"last_attempt,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	QueueDepth          int        `json:"queue_depth"`
+	Interval            string     `json:"interval"`
+}
+
+type handler struct {
+	reporter    Reporter
+	maxFailures int
+}
+
+// NewHandler creates an http.Handler serving the reporter Status as JSON. It answers with
+// 503 Service Unavailable when the consecutive failures exceed maxFailures, 200 otherwise
+func NewHandler(reporter Reporter, maxFailures int) http.Handler {
+	return &handler{
+		reporter:    reporter,
+		maxFailures: maxFailures,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s := h.reporter.Status()
+
+	body := statusJSON{
+		Healthy:             s.ConsecutiveFailures <= h.maxFailures,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		QueueDepth:          s.QueueDepth,
+		Interval:            s.Interval.String(),
+	}
+	if !s.LastAttempt.IsZero() {
+		body.LastAttempt = &s.LastAttempt
+	}
+	if !s.LastSuccess.IsZero() {
+		body.LastSuccess = &s.LastSuccess
+	}
+	if s.LastError != nil {
+		body.LastError = s.LastError.Error()
+	}
+
+	code := http.StatusOK
+	if !body.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
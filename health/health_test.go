@@ -0,0 +1,93 @@
+package health
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reporterFunc func() Status
+
+func (f reporterFunc) Status() Status { return f() }
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	serve := func(s Status, maxFailures int) (int, map[string]interface{}) {
+		h := NewHandler(reporterFunc(func() Status { return s }), maxFailures)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	t.Run("OK - Healthy", func(t *testing.T) {
+		code, body := serve(Status{
+			LastAttempt: now,
+			LastSuccess: now,
+			QueueDepth:  3,
+			Interval:    time.Minute,
+		}, 2)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]interface{}{
+			"healthy":              true,
+			"last_attempt":         "2020-01-02T03:04:05Z",
+			"last_success":         "2020-01-02T03:04:05Z",
+			"consecutive_failures": 0.0,
+			"queue_depth":          3.0,
+			"interval":             "1m0s",
+		}, body)
+	})
+
+	t.Run("OK - Failures under threshold", func(t *testing.T) {
+		code, body := serve(Status{
+			LastAttempt:         now,
+			ConsecutiveFailures: 2,
+			LastError:           errors.New("something happened"),
+			Interval:            time.Minute,
+		}, 2)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, body["healthy"])
+		assert.Equal(t, "something happened", body["last_error"])
+		assert.NotContains(t, body, "last_success")
+	})
+
+	t.Run("NOK - Failures over threshold", func(t *testing.T) {
+		code, body := serve(Status{
+			LastAttempt:         now,
+			ConsecutiveFailures: 3,
+			LastError:           errors.New("something happened"),
+			Interval:            time.Minute,
+		}, 2)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, false, body["healthy"])
+		assert.Equal(t, 3.0, body["consecutive_failures"])
+	})
+}
@@ -0,0 +1,132 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/go-metrics"
+)
+
+// BatchResult describes a PutMetricData attempt
+type BatchResult struct {
+	Namespace string
+	Batch     []*cloudwatch.MetricDatum
+	Attempt   int
+	Duration  time.Duration
+	Err       error
+}
+
+// IntervalResult summarizes the publication of one interval
+type IntervalResult struct {
+	Namespaces    []string
+	Batches       int
+	FailedBatches int
+	Datums        int
+	FailedDatums  int
+	Duration      time.Duration
+}
+
+// ExpiredMetric describes a metric no longer published because it did not change for too long
+type ExpiredMetric struct {
+	Registry     metrics.Registry
+	Namespace    string
+	Name         string
+	LastChanged  time.Time
+	Unregistered bool
+}
+
+// hooks holds the callbacks invoked on publication events
+type hooks struct {
+	batchSent        []func(BatchResult)
+	batchError       []func(BatchResult)
+	intervalComplete []func(IntervalResult)
+	metricExpired    []func(ExpiredMetric)
+	dispatch         func(f func())
+	async            int
+	logger           Logger
+}
+
+// start runs the hooks in a dedicated goroutine when they are asynchronous, until the returned
+// function is called. Hooks run synchronously otherwise
+func (h *hooks) start(ctx context.Context) (stop func()) {
+	if h.async <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	h.dispatch = newAsyncDispatcher(ctx, h.async, h.logger)
+	return func() {
+		cancel()
+		h.dispatch = dispatchSync
+	}
+}
+
+func (h *hooks) batchDone(r BatchResult) {
+	fns := h.batchSent
+	if r.Err != nil {
+		fns = h.batchError
+	}
+
+	for _, f := range fns {
+		f := f
+		h.dispatch(func() { f(r) })
+	}
+}
+
+func (h *hooks) intervalDone(r IntervalResult) {
+	for _, f := range h.intervalComplete {
+		f := f
+		h.dispatch(func() { f(r) })
+	}
+}
+
+func (h *hooks) expired(m ExpiredMetric) {
+	for _, f := range h.metricExpired {
+		f := f
+		h.dispatch(func() { f(m) })
+	}
+}
+
+func dispatchSync(f func()) {
+	f()
+}
+
+// newAsyncDispatcher runs the hooks in a dedicated goroutine until ctx is done. Events are
+// dropped when size events are already waiting, so that slow hooks never block the publisher
+func newAsyncDispatcher(ctx context.Context, size int, logger Logger) func(f func()) {
+	ch := make(chan func(), size)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case f := <-ch:
+				f()
+			}
+		}
+	}()
+
+	return func(f func()) {
+		select {
+		case ch <- f:
+		default:
+			logger.Warnf("hook dispatcher is full, dropping event")
+		}
+	}
+}
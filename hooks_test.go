@@ -0,0 +1,149 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestPublisher__Hooks(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, registry.Register(fmt.Sprintf("counter-%02d", i), metrics.NewCounter()))
+	}
+
+	logger, _ := test.NewNullLogger()
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		if len(input.MetricData) < batchSize {
+			return nil, errors.New("something happened")
+		}
+		return nil, nil
+	})
+
+	sent := []BatchResult{}
+	failed := []BatchResult{}
+	intervals := []IntervalResult{}
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(cw),
+		WithLogger(logger),
+		OnBatchSent(func(r BatchResult) { sent = append(sent, r) }),
+		OnBatchError(func(r BatchResult) { failed = append(failed, r) }),
+		OnIntervalComplete(func(r IntervalResult) { intervals = append(intervals, r) }),
+	).(*publisher)
+
+	p.flush(time.Time{})
+
+	require.Len(t, sent, 1)
+	assert.Equal(t, "nmsp", sent[0].Namespace)
+	assert.Len(t, sent[0].Batch, batchSize)
+	assert.Equal(t, 1, sent[0].Attempt)
+	assert.NoError(t, sent[0].Err)
+
+	require.Len(t, failed, 1)
+	assert.Equal(t, "nmsp", failed[0].Namespace)
+	assert.Len(t, failed[0].Batch, 5)
+	assert.Equal(t, 1, failed[0].Attempt)
+	assert.EqualError(t, failed[0].Err, "something happened")
+
+	require.Len(t, intervals, 1)
+	assert.NotZero(t, intervals[0].Duration)
+	intervals[0].Duration = 0
+	assert.Equal(t, IntervalResult{
+		Namespaces:    []string{"nmsp"},
+		Batches:       2,
+		FailedBatches: 1,
+		Datums:        25,
+		FailedDatums:  5,
+	}, intervals[0])
+}
+
+func TestAsyncDispatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger, hook := test.NewNullLogger()
+	dispatch := newAsyncDispatcher(ctx, 1, NewLogrusLogger(logger))
+
+	t.Run("OK - Runs in background", func(t *testing.T) {
+		done := make(chan struct{})
+		dispatch(func() { close(done) })
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("hook was not called")
+		}
+	})
+
+	t.Run("OK - Drops events when full", func(t *testing.T) {
+		block := make(chan struct{})
+		started := make(chan struct{})
+		dispatch(func() {
+			close(started)
+			<-block
+		})
+		<-started
+
+		dispatch(func() {})
+		dispatch(func() {})
+		close(block)
+
+		require.Len(t, hook.Entries, 1)
+		assert.Equal(t, logrus.WarnLevel, hook.Entries[0].Level)
+		assert.Equal(t, "hook dispatcher is full, dropping event", hook.Entries[0].Message)
+	})
+}
+
+func TestHooks__Start(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	h := &hooks{dispatch: dispatchSync, async: 1, logger: NewLogrusLogger(logger)}
+
+	// Hooks run synchronously until the publisher starts
+	called := false
+	h.dispatch(func() { called = true })
+	assert.True(t, called)
+
+	stop := h.start(context.Background())
+	done := make(chan struct{})
+	h.dispatch(func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hook was not called")
+	}
+
+	stop()
+	called = false
+	h.dispatch(func() { called = true })
+	assert.True(t, called)
+}
@@ -0,0 +1,65 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logging interface used by the Publisher
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	WithField(key string, value interface{}) Logger
+	WithError(err error) Logger
+}
+
+// NewLogrusLogger adapts a logrus logger to Logger
+func NewLogrusLogger(l logrus.FieldLogger) Logger {
+	return logrusLogger{l: l}
+}
+
+type logrusLogger struct {
+	l logrus.FieldLogger
+}
+
+func (l logrusLogger) Debugf(format string, args ...interface{}) { l.l.Debugf(format, args...) }
+func (l logrusLogger) Infof(format string, args ...interface{})  { l.l.Infof(format, args...) }
+func (l logrusLogger) Warnf(format string, args ...interface{})  { l.l.Warnf(format, args...) }
+func (l logrusLogger) Errorf(format string, args ...interface{}) { l.l.Errorf(format, args...) }
+
+func (l logrusLogger) WithField(key string, value interface{}) Logger {
+	return logrusLogger{l: l.l.WithField(key, value)}
+}
+
+func (l logrusLogger) WithError(err error) Logger {
+	return logrusLogger{l: l.l.WithError(err)}
+}
+
+// NopLogger returns a Logger discarding everything
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{})          {}
+func (nopLogger) Infof(string, ...interface{})           {}
+func (nopLogger) Warnf(string, ...interface{})           {}
+func (nopLogger) Errorf(string, ...interface{})          {}
+func (n nopLogger) WithField(string, interface{}) Logger { return n }
+func (n nopLogger) WithError(error) Logger               { return n }
@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// NewSlogLogger adapts a log/slog logger to Logger
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) log(level slog.Level, format string, args ...interface{}) {
+	if !l.l.Enabled(context.Background(), level) {
+		return
+	}
+	l.l.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+func (l slogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, format, args...)
+}
+func (l slogLogger) Infof(format string, args ...interface{}) { l.log(slog.LevelInfo, format, args...) }
+func (l slogLogger) Warnf(format string, args ...interface{}) { l.log(slog.LevelWarn, format, args...) }
+func (l slogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args...)
+}
+
+func (l slogLogger) WithField(key string, value interface{}) Logger {
+	return slogLogger{l: l.l.With(key, value)}
+}
+
+func (l slogLogger) WithError(err error) Logger {
+	return slogLogger{l: l.l.With("error", err)}
+}
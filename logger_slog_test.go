@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d", 2)
+	logger.Warnf("warn %d", 3)
+	logger.WithField("k", "v").WithError(errors.New("something happened")).Errorf("error %d", 4)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	entries := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &entries[i]))
+	}
+
+	assert.Equal(t, "INFO", entries[0]["level"])
+	assert.Equal(t, "info 2", entries[0]["msg"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "warn 3", entries[1]["msg"])
+	assert.Equal(t, "ERROR", entries[2]["level"])
+	assert.Equal(t, "error 4", entries[2]["msg"])
+	assert.Equal(t, "v", entries[2]["k"])
+	assert.Equal(t, "something happened", entries[2]["error"])
+}
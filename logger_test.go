@@ -0,0 +1,81 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+)
+
+func TestLogrusLogger(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	l.SetLevel(logrus.DebugLevel)
+	logger := NewLogrusLogger(l)
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d", 2)
+	logger.Warnf("warn %d", 3)
+	logger.WithField("k", "v").WithError(errors.New("something happened")).Errorf("error %d", 4)
+
+	require.Len(t, hook.Entries, 4)
+	assert.Equal(t, logrus.DebugLevel, hook.Entries[0].Level)
+	assert.Equal(t, "debug 1", hook.Entries[0].Message)
+	assert.Equal(t, logrus.InfoLevel, hook.Entries[1].Level)
+	assert.Equal(t, "info 2", hook.Entries[1].Message)
+	assert.Equal(t, logrus.WarnLevel, hook.Entries[2].Level)
+	assert.Equal(t, "warn 3", hook.Entries[2].Message)
+
+	entry := hook.Entries[3]
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "error 4", entry.Message)
+	assert.Equal(t, "v", entry.Data["k"])
+	assert.EqualError(t, entry.Data[logrus.ErrorKey].(error), "something happened")
+}
+
+func TestNopLogger(t *testing.T) {
+	logger := NopLogger()
+	assert.NotPanics(t, func() {
+		logger.Debugf("debug")
+		logger.Infof("info")
+		logger.Warnf("warn")
+		logger.WithField("k", "v").WithError(errors.New("something happened")).Errorf("error")
+	})
+}
+
+func TestWithLogger__Nil(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	s := getSettings([]Option{WithClient(mock.NewCloudWatchMock(mc)), WithLogger(nil)})
+	assert.Nil(t, s.Logger)
+
+	p := newPublisher(nil, "nmsp", s)
+	require.NotNil(t, p.logger)
+	assert.NotPanics(t, func() { p.logger.Debugf("debug") })
+}
+
+func TestNewLogger(t *testing.T) {
+	l, ok := newLogger().(logrusLogger)
+	require.True(t, ok)
+	// logrus would report the adapter as the caller of every entry
+	assert.False(t, l.l.(*logrus.Logger).ReportCaller)
+}
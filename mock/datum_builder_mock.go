@@ -22,6 +22,12 @@ type DatumBuilderMock struct {
 	beforeBuildCounterDataCounter uint64
 	BuildCounterDataMock          mDatumBuilderMockBuildCounterData
 
+	funcBuildEWMAData          func(v metrics.EWMA, name string) (mpa1 []*cloudwatch.MetricDatum)
+	inspectFuncBuildEWMAData   func(v metrics.EWMA, name string)
+	afterBuildEWMADataCounter  uint64
+	beforeBuildEWMADataCounter uint64
+	BuildEWMADataMock          mDatumBuilderMockBuildEWMAData
+
 	funcBuildGaugeData          func(v metrics.Gauge, name string) (mpa1 []*cloudwatch.MetricDatum)
 	inspectFuncBuildGaugeData   func(v metrics.Gauge, name string)
 	afterBuildGaugeDataCounter  uint64
@@ -34,6 +40,12 @@ type DatumBuilderMock struct {
 	beforeBuildGaugeFloat64DataCounter uint64
 	BuildGaugeFloat64DataMock          mDatumBuilderMockBuildGaugeFloat64Data
 
+	funcBuildHealthcheckData          func(v metrics.Healthcheck, name string) (mpa1 []*cloudwatch.MetricDatum)
+	inspectFuncBuildHealthcheckData   func(v metrics.Healthcheck, name string)
+	afterBuildHealthcheckDataCounter  uint64
+	beforeBuildHealthcheckDataCounter uint64
+	BuildHealthcheckDataMock          mDatumBuilderMockBuildHealthcheckData
+
 	funcBuildHistogramData          func(v metrics.Histogram, name string) (mpa1 []*cloudwatch.MetricDatum)
 	inspectFuncBuildHistogramData   func(v metrics.Histogram, name string)
 	afterBuildHistogramDataCounter  uint64
@@ -63,12 +75,18 @@ func NewDatumBuilderMock(t minimock.Tester) *DatumBuilderMock {
 	m.BuildCounterDataMock = mDatumBuilderMockBuildCounterData{mock: m}
 	m.BuildCounterDataMock.callArgs = []*DatumBuilderMockBuildCounterDataParams{}
 
+	m.BuildEWMADataMock = mDatumBuilderMockBuildEWMAData{mock: m}
+	m.BuildEWMADataMock.callArgs = []*DatumBuilderMockBuildEWMADataParams{}
+
 	m.BuildGaugeDataMock = mDatumBuilderMockBuildGaugeData{mock: m}
 	m.BuildGaugeDataMock.callArgs = []*DatumBuilderMockBuildGaugeDataParams{}
 
 	m.BuildGaugeFloat64DataMock = mDatumBuilderMockBuildGaugeFloat64Data{mock: m}
 	m.BuildGaugeFloat64DataMock.callArgs = []*DatumBuilderMockBuildGaugeFloat64DataParams{}
 
+	m.BuildHealthcheckDataMock = mDatumBuilderMockBuildHealthcheckData{mock: m}
+	m.BuildHealthcheckDataMock.callArgs = []*DatumBuilderMockBuildHealthcheckDataParams{}
+
 	m.BuildHistogramDataMock = mDatumBuilderMockBuildHistogramData{mock: m}
 	m.BuildHistogramDataMock.callArgs = []*DatumBuilderMockBuildHistogramDataParams{}
 
@@ -297,6 +315,222 @@ func (m *DatumBuilderMock) MinimockBuildCounterDataInspect() {
 	}
 }
 
+type mDatumBuilderMockBuildEWMAData struct {
+	mock               *DatumBuilderMock
+	defaultExpectation *DatumBuilderMockBuildEWMADataExpectation
+	expectations       []*DatumBuilderMockBuildEWMADataExpectation
+
+	callArgs []*DatumBuilderMockBuildEWMADataParams
+	mutex    sync.RWMutex
+}
+
+// DatumBuilderMockBuildEWMADataExpectation specifies expectation struct of the DatumBuilder.BuildEWMAData
+type DatumBuilderMockBuildEWMADataExpectation struct {
+	mock    *DatumBuilderMock
+	params  *DatumBuilderMockBuildEWMADataParams
+	results *DatumBuilderMockBuildEWMADataResults
+	Counter uint64
+}
+
+// DatumBuilderMockBuildEWMADataParams contains parameters of the DatumBuilder.BuildEWMAData
+type DatumBuilderMockBuildEWMADataParams struct {
+	v    metrics.EWMA
+	name string
+}
+
+// DatumBuilderMockBuildEWMADataResults contains results of the DatumBuilder.BuildEWMAData
+type DatumBuilderMockBuildEWMADataResults struct {
+	mpa1 []*cloudwatch.MetricDatum
+}
+
+// Expect sets up expected params for DatumBuilder.BuildEWMAData
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) Expect(v metrics.EWMA, name string) *mDatumBuilderMockBuildEWMAData {
+	if mmBuildEWMAData.mock.funcBuildEWMAData != nil {
+		mmBuildEWMAData.mock.t.Fatalf("DatumBuilderMock.BuildEWMAData mock is already set by Set")
+	}
+
+	if mmBuildEWMAData.defaultExpectation == nil {
+		mmBuildEWMAData.defaultExpectation = &DatumBuilderMockBuildEWMADataExpectation{}
+	}
+
+	mmBuildEWMAData.defaultExpectation.params = &DatumBuilderMockBuildEWMADataParams{v, name}
+	for _, e := range mmBuildEWMAData.expectations {
+		if minimock.Equal(e.params, mmBuildEWMAData.defaultExpectation.params) {
+			mmBuildEWMAData.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmBuildEWMAData.defaultExpectation.params)
+		}
+	}
+
+	return mmBuildEWMAData
+}
+
+// Inspect accepts an inspector function that has same arguments as the DatumBuilder.BuildEWMAData
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) Inspect(f func(v metrics.EWMA, name string)) *mDatumBuilderMockBuildEWMAData {
+	if mmBuildEWMAData.mock.inspectFuncBuildEWMAData != nil {
+		mmBuildEWMAData.mock.t.Fatalf("Inspect function is already set for DatumBuilderMock.BuildEWMAData")
+	}
+
+	mmBuildEWMAData.mock.inspectFuncBuildEWMAData = f
+
+	return mmBuildEWMAData
+}
+
+// Return sets up results that will be returned by DatumBuilder.BuildEWMAData
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) Return(mpa1 []*cloudwatch.MetricDatum) *DatumBuilderMock {
+	if mmBuildEWMAData.mock.funcBuildEWMAData != nil {
+		mmBuildEWMAData.mock.t.Fatalf("DatumBuilderMock.BuildEWMAData mock is already set by Set")
+	}
+
+	if mmBuildEWMAData.defaultExpectation == nil {
+		mmBuildEWMAData.defaultExpectation = &DatumBuilderMockBuildEWMADataExpectation{mock: mmBuildEWMAData.mock}
+	}
+	mmBuildEWMAData.defaultExpectation.results = &DatumBuilderMockBuildEWMADataResults{mpa1}
+	return mmBuildEWMAData.mock
+}
+
+//Set uses given function f to mock the DatumBuilder.BuildEWMAData method
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) Set(f func(v metrics.EWMA, name string) (mpa1 []*cloudwatch.MetricDatum)) *DatumBuilderMock {
+	if mmBuildEWMAData.defaultExpectation != nil {
+		mmBuildEWMAData.mock.t.Fatalf("Default expectation is already set for the DatumBuilder.BuildEWMAData method")
+	}
+
+	if len(mmBuildEWMAData.expectations) > 0 {
+		mmBuildEWMAData.mock.t.Fatalf("Some expectations are already set for the DatumBuilder.BuildEWMAData method")
+	}
+
+	mmBuildEWMAData.mock.funcBuildEWMAData = f
+	return mmBuildEWMAData.mock
+}
+
+// When sets expectation for the DatumBuilder.BuildEWMAData which will trigger the result defined by the following
+// Then helper
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) When(v metrics.EWMA, name string) *DatumBuilderMockBuildEWMADataExpectation {
+	if mmBuildEWMAData.mock.funcBuildEWMAData != nil {
+		mmBuildEWMAData.mock.t.Fatalf("DatumBuilderMock.BuildEWMAData mock is already set by Set")
+	}
+
+	expectation := &DatumBuilderMockBuildEWMADataExpectation{
+		mock:   mmBuildEWMAData.mock,
+		params: &DatumBuilderMockBuildEWMADataParams{v, name},
+	}
+	mmBuildEWMAData.expectations = append(mmBuildEWMAData.expectations, expectation)
+	return expectation
+}
+
+// Then sets up DatumBuilder.BuildEWMAData return parameters for the expectation previously defined by the When method
+func (e *DatumBuilderMockBuildEWMADataExpectation) Then(mpa1 []*cloudwatch.MetricDatum) *DatumBuilderMock {
+	e.results = &DatumBuilderMockBuildEWMADataResults{mpa1}
+	return e.mock
+}
+
+// BuildEWMAData implements cloudmetrics.DatumBuilder
+func (mmBuildEWMAData *DatumBuilderMock) BuildEWMAData(v metrics.EWMA, name string) (mpa1 []*cloudwatch.MetricDatum) {
+	mm_atomic.AddUint64(&mmBuildEWMAData.beforeBuildEWMADataCounter, 1)
+	defer mm_atomic.AddUint64(&mmBuildEWMAData.afterBuildEWMADataCounter, 1)
+
+	if mmBuildEWMAData.inspectFuncBuildEWMAData != nil {
+		mmBuildEWMAData.inspectFuncBuildEWMAData(v, name)
+	}
+
+	mm_params := &DatumBuilderMockBuildEWMADataParams{v, name}
+
+	// Record call args
+	mmBuildEWMAData.BuildEWMADataMock.mutex.Lock()
+	mmBuildEWMAData.BuildEWMADataMock.callArgs = append(mmBuildEWMAData.BuildEWMADataMock.callArgs, mm_params)
+	mmBuildEWMAData.BuildEWMADataMock.mutex.Unlock()
+
+	for _, e := range mmBuildEWMAData.BuildEWMADataMock.expectations {
+		if minimock.Equal(e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.mpa1
+		}
+	}
+
+	if mmBuildEWMAData.BuildEWMADataMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmBuildEWMAData.BuildEWMADataMock.defaultExpectation.Counter, 1)
+		mm_want := mmBuildEWMAData.BuildEWMADataMock.defaultExpectation.params
+		mm_got := DatumBuilderMockBuildEWMADataParams{v, name}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmBuildEWMAData.t.Errorf("DatumBuilderMock.BuildEWMAData got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmBuildEWMAData.BuildEWMADataMock.defaultExpectation.results
+		if mm_results == nil {
+			mmBuildEWMAData.t.Fatal("No results are set for the DatumBuilderMock.BuildEWMAData")
+		}
+		return (*mm_results).mpa1
+	}
+	if mmBuildEWMAData.funcBuildEWMAData != nil {
+		return mmBuildEWMAData.funcBuildEWMAData(v, name)
+	}
+	mmBuildEWMAData.t.Fatalf("Unexpected call to DatumBuilderMock.BuildEWMAData. %v %v", v, name)
+	return
+}
+
+// BuildEWMADataAfterCounter returns a count of finished DatumBuilderMock.BuildEWMAData invocations
+func (mmBuildEWMAData *DatumBuilderMock) BuildEWMADataAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmBuildEWMAData.afterBuildEWMADataCounter)
+}
+
+// BuildEWMADataBeforeCounter returns a count of DatumBuilderMock.BuildEWMAData invocations
+func (mmBuildEWMAData *DatumBuilderMock) BuildEWMADataBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmBuildEWMAData.beforeBuildEWMADataCounter)
+}
+
+// Calls returns a list of arguments used in each call to DatumBuilderMock.BuildEWMAData.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmBuildEWMAData *mDatumBuilderMockBuildEWMAData) Calls() []*DatumBuilderMockBuildEWMADataParams {
+	mmBuildEWMAData.mutex.RLock()
+
+	argCopy := make([]*DatumBuilderMockBuildEWMADataParams, len(mmBuildEWMAData.callArgs))
+	copy(argCopy, mmBuildEWMAData.callArgs)
+
+	mmBuildEWMAData.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockBuildEWMADataDone returns true if the count of the BuildEWMAData invocations corresponds
+// the number of defined expectations
+func (m *DatumBuilderMock) MinimockBuildEWMADataDone() bool {
+	for _, e := range m.BuildEWMADataMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.BuildEWMADataMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterBuildEWMADataCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcBuildEWMAData != nil && mm_atomic.LoadUint64(&m.afterBuildEWMADataCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockBuildEWMADataInspect logs each unmet expectation
+func (m *DatumBuilderMock) MinimockBuildEWMADataInspect() {
+	for _, e := range m.BuildEWMADataMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to DatumBuilderMock.BuildEWMAData with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.BuildEWMADataMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterBuildEWMADataCounter) < 1 {
+		if m.BuildEWMADataMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to DatumBuilderMock.BuildEWMAData")
+		} else {
+			m.t.Errorf("Expected call to DatumBuilderMock.BuildEWMAData with params: %#v", *m.BuildEWMADataMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcBuildEWMAData != nil && mm_atomic.LoadUint64(&m.afterBuildEWMADataCounter) < 1 {
+		m.t.Error("Expected call to DatumBuilderMock.BuildEWMAData")
+	}
+}
+
 type mDatumBuilderMockBuildGaugeData struct {
 	mock               *DatumBuilderMock
 	defaultExpectation *DatumBuilderMockBuildGaugeDataExpectation
@@ -729,6 +963,222 @@ func (m *DatumBuilderMock) MinimockBuildGaugeFloat64DataInspect() {
 	}
 }
 
+type mDatumBuilderMockBuildHealthcheckData struct {
+	mock               *DatumBuilderMock
+	defaultExpectation *DatumBuilderMockBuildHealthcheckDataExpectation
+	expectations       []*DatumBuilderMockBuildHealthcheckDataExpectation
+
+	callArgs []*DatumBuilderMockBuildHealthcheckDataParams
+	mutex    sync.RWMutex
+}
+
+// DatumBuilderMockBuildHealthcheckDataExpectation specifies expectation struct of the DatumBuilder.BuildHealthcheckData
+type DatumBuilderMockBuildHealthcheckDataExpectation struct {
+	mock    *DatumBuilderMock
+	params  *DatumBuilderMockBuildHealthcheckDataParams
+	results *DatumBuilderMockBuildHealthcheckDataResults
+	Counter uint64
+}
+
+// DatumBuilderMockBuildHealthcheckDataParams contains parameters of the DatumBuilder.BuildHealthcheckData
+type DatumBuilderMockBuildHealthcheckDataParams struct {
+	v    metrics.Healthcheck
+	name string
+}
+
+// DatumBuilderMockBuildHealthcheckDataResults contains results of the DatumBuilder.BuildHealthcheckData
+type DatumBuilderMockBuildHealthcheckDataResults struct {
+	mpa1 []*cloudwatch.MetricDatum
+}
+
+// Expect sets up expected params for DatumBuilder.BuildHealthcheckData
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) Expect(v metrics.Healthcheck, name string) *mDatumBuilderMockBuildHealthcheckData {
+	if mmBuildHealthcheckData.mock.funcBuildHealthcheckData != nil {
+		mmBuildHealthcheckData.mock.t.Fatalf("DatumBuilderMock.BuildHealthcheckData mock is already set by Set")
+	}
+
+	if mmBuildHealthcheckData.defaultExpectation == nil {
+		mmBuildHealthcheckData.defaultExpectation = &DatumBuilderMockBuildHealthcheckDataExpectation{}
+	}
+
+	mmBuildHealthcheckData.defaultExpectation.params = &DatumBuilderMockBuildHealthcheckDataParams{v, name}
+	for _, e := range mmBuildHealthcheckData.expectations {
+		if minimock.Equal(e.params, mmBuildHealthcheckData.defaultExpectation.params) {
+			mmBuildHealthcheckData.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmBuildHealthcheckData.defaultExpectation.params)
+		}
+	}
+
+	return mmBuildHealthcheckData
+}
+
+// Inspect accepts an inspector function that has same arguments as the DatumBuilder.BuildHealthcheckData
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) Inspect(f func(v metrics.Healthcheck, name string)) *mDatumBuilderMockBuildHealthcheckData {
+	if mmBuildHealthcheckData.mock.inspectFuncBuildHealthcheckData != nil {
+		mmBuildHealthcheckData.mock.t.Fatalf("Inspect function is already set for DatumBuilderMock.BuildHealthcheckData")
+	}
+
+	mmBuildHealthcheckData.mock.inspectFuncBuildHealthcheckData = f
+
+	return mmBuildHealthcheckData
+}
+
+// Return sets up results that will be returned by DatumBuilder.BuildHealthcheckData
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) Return(mpa1 []*cloudwatch.MetricDatum) *DatumBuilderMock {
+	if mmBuildHealthcheckData.mock.funcBuildHealthcheckData != nil {
+		mmBuildHealthcheckData.mock.t.Fatalf("DatumBuilderMock.BuildHealthcheckData mock is already set by Set")
+	}
+
+	if mmBuildHealthcheckData.defaultExpectation == nil {
+		mmBuildHealthcheckData.defaultExpectation = &DatumBuilderMockBuildHealthcheckDataExpectation{mock: mmBuildHealthcheckData.mock}
+	}
+	mmBuildHealthcheckData.defaultExpectation.results = &DatumBuilderMockBuildHealthcheckDataResults{mpa1}
+	return mmBuildHealthcheckData.mock
+}
+
+//Set uses given function f to mock the DatumBuilder.BuildHealthcheckData method
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) Set(f func(v metrics.Healthcheck, name string) (mpa1 []*cloudwatch.MetricDatum)) *DatumBuilderMock {
+	if mmBuildHealthcheckData.defaultExpectation != nil {
+		mmBuildHealthcheckData.mock.t.Fatalf("Default expectation is already set for the DatumBuilder.BuildHealthcheckData method")
+	}
+
+	if len(mmBuildHealthcheckData.expectations) > 0 {
+		mmBuildHealthcheckData.mock.t.Fatalf("Some expectations are already set for the DatumBuilder.BuildHealthcheckData method")
+	}
+
+	mmBuildHealthcheckData.mock.funcBuildHealthcheckData = f
+	return mmBuildHealthcheckData.mock
+}
+
+// When sets expectation for the DatumBuilder.BuildHealthcheckData which will trigger the result defined by the following
+// Then helper
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) When(v metrics.Healthcheck, name string) *DatumBuilderMockBuildHealthcheckDataExpectation {
+	if mmBuildHealthcheckData.mock.funcBuildHealthcheckData != nil {
+		mmBuildHealthcheckData.mock.t.Fatalf("DatumBuilderMock.BuildHealthcheckData mock is already set by Set")
+	}
+
+	expectation := &DatumBuilderMockBuildHealthcheckDataExpectation{
+		mock:   mmBuildHealthcheckData.mock,
+		params: &DatumBuilderMockBuildHealthcheckDataParams{v, name},
+	}
+	mmBuildHealthcheckData.expectations = append(mmBuildHealthcheckData.expectations, expectation)
+	return expectation
+}
+
+// Then sets up DatumBuilder.BuildHealthcheckData return parameters for the expectation previously defined by the When method
+func (e *DatumBuilderMockBuildHealthcheckDataExpectation) Then(mpa1 []*cloudwatch.MetricDatum) *DatumBuilderMock {
+	e.results = &DatumBuilderMockBuildHealthcheckDataResults{mpa1}
+	return e.mock
+}
+
+// BuildHealthcheckData implements cloudmetrics.DatumBuilder
+func (mmBuildHealthcheckData *DatumBuilderMock) BuildHealthcheckData(v metrics.Healthcheck, name string) (mpa1 []*cloudwatch.MetricDatum) {
+	mm_atomic.AddUint64(&mmBuildHealthcheckData.beforeBuildHealthcheckDataCounter, 1)
+	defer mm_atomic.AddUint64(&mmBuildHealthcheckData.afterBuildHealthcheckDataCounter, 1)
+
+	if mmBuildHealthcheckData.inspectFuncBuildHealthcheckData != nil {
+		mmBuildHealthcheckData.inspectFuncBuildHealthcheckData(v, name)
+	}
+
+	mm_params := &DatumBuilderMockBuildHealthcheckDataParams{v, name}
+
+	// Record call args
+	mmBuildHealthcheckData.BuildHealthcheckDataMock.mutex.Lock()
+	mmBuildHealthcheckData.BuildHealthcheckDataMock.callArgs = append(mmBuildHealthcheckData.BuildHealthcheckDataMock.callArgs, mm_params)
+	mmBuildHealthcheckData.BuildHealthcheckDataMock.mutex.Unlock()
+
+	for _, e := range mmBuildHealthcheckData.BuildHealthcheckDataMock.expectations {
+		if minimock.Equal(e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.mpa1
+		}
+	}
+
+	if mmBuildHealthcheckData.BuildHealthcheckDataMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmBuildHealthcheckData.BuildHealthcheckDataMock.defaultExpectation.Counter, 1)
+		mm_want := mmBuildHealthcheckData.BuildHealthcheckDataMock.defaultExpectation.params
+		mm_got := DatumBuilderMockBuildHealthcheckDataParams{v, name}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmBuildHealthcheckData.t.Errorf("DatumBuilderMock.BuildHealthcheckData got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmBuildHealthcheckData.BuildHealthcheckDataMock.defaultExpectation.results
+		if mm_results == nil {
+			mmBuildHealthcheckData.t.Fatal("No results are set for the DatumBuilderMock.BuildHealthcheckData")
+		}
+		return (*mm_results).mpa1
+	}
+	if mmBuildHealthcheckData.funcBuildHealthcheckData != nil {
+		return mmBuildHealthcheckData.funcBuildHealthcheckData(v, name)
+	}
+	mmBuildHealthcheckData.t.Fatalf("Unexpected call to DatumBuilderMock.BuildHealthcheckData. %v %v", v, name)
+	return
+}
+
+// BuildHealthcheckDataAfterCounter returns a count of finished DatumBuilderMock.BuildHealthcheckData invocations
+func (mmBuildHealthcheckData *DatumBuilderMock) BuildHealthcheckDataAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmBuildHealthcheckData.afterBuildHealthcheckDataCounter)
+}
+
+// BuildHealthcheckDataBeforeCounter returns a count of DatumBuilderMock.BuildHealthcheckData invocations
+func (mmBuildHealthcheckData *DatumBuilderMock) BuildHealthcheckDataBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmBuildHealthcheckData.beforeBuildHealthcheckDataCounter)
+}
+
+// Calls returns a list of arguments used in each call to DatumBuilderMock.BuildHealthcheckData.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmBuildHealthcheckData *mDatumBuilderMockBuildHealthcheckData) Calls() []*DatumBuilderMockBuildHealthcheckDataParams {
+	mmBuildHealthcheckData.mutex.RLock()
+
+	argCopy := make([]*DatumBuilderMockBuildHealthcheckDataParams, len(mmBuildHealthcheckData.callArgs))
+	copy(argCopy, mmBuildHealthcheckData.callArgs)
+
+	mmBuildHealthcheckData.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockBuildHealthcheckDataDone returns true if the count of the BuildHealthcheckData invocations corresponds
+// the number of defined expectations
+func (m *DatumBuilderMock) MinimockBuildHealthcheckDataDone() bool {
+	for _, e := range m.BuildHealthcheckDataMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.BuildHealthcheckDataMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterBuildHealthcheckDataCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcBuildHealthcheckData != nil && mm_atomic.LoadUint64(&m.afterBuildHealthcheckDataCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockBuildHealthcheckDataInspect logs each unmet expectation
+func (m *DatumBuilderMock) MinimockBuildHealthcheckDataInspect() {
+	for _, e := range m.BuildHealthcheckDataMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to DatumBuilderMock.BuildHealthcheckData with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.BuildHealthcheckDataMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterBuildHealthcheckDataCounter) < 1 {
+		if m.BuildHealthcheckDataMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to DatumBuilderMock.BuildHealthcheckData")
+		} else {
+			m.t.Errorf("Expected call to DatumBuilderMock.BuildHealthcheckData with params: %#v", *m.BuildHealthcheckDataMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcBuildHealthcheckData != nil && mm_atomic.LoadUint64(&m.afterBuildHealthcheckDataCounter) < 1 {
+		m.t.Error("Expected call to DatumBuilderMock.BuildHealthcheckData")
+	}
+}
+
 type mDatumBuilderMockBuildHistogramData struct {
 	mock               *DatumBuilderMock
 	defaultExpectation *DatumBuilderMockBuildHistogramDataExpectation
@@ -1382,10 +1832,14 @@ func (m *DatumBuilderMock) MinimockFinish() {
 	if !m.minimockDone() {
 		m.MinimockBuildCounterDataInspect()
 
+		m.MinimockBuildEWMADataInspect()
+
 		m.MinimockBuildGaugeDataInspect()
 
 		m.MinimockBuildGaugeFloat64DataInspect()
 
+		m.MinimockBuildHealthcheckDataInspect()
+
 		m.MinimockBuildHistogramDataInspect()
 
 		m.MinimockBuildMeterDataInspect()
@@ -1415,8 +1869,10 @@ func (m *DatumBuilderMock) minimockDone() bool {
 	done := true
 	return done &&
 		m.MinimockBuildCounterDataDone() &&
+		m.MinimockBuildEWMADataDone() &&
 		m.MinimockBuildGaugeDataDone() &&
 		m.MinimockBuildGaugeFloat64DataDone() &&
+		m.MinimockBuildHealthcheckDataDone() &&
 		m.MinimockBuildHistogramDataDone() &&
 		m.MinimockBuildMeterDataDone() &&
 		m.MinimockBuildTimerDataDone()
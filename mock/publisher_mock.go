@@ -7,6 +7,7 @@ import (
 	mm_time "time"
 
 	"github.com/gojuno/minimock/v3"
+	"github.com/weareyolo/cloudmetrics/health"
 )
 
 // PublisherMock implements cloudmetrics.Publisher
@@ -18,6 +19,12 @@ type PublisherMock struct {
 	afterPublishCounter  uint64
 	beforePublishCounter uint64
 	PublishMock          mPublisherMockPublish
+
+	funcStatus          func() (s1 health.Status)
+	inspectFuncStatus   func()
+	afterStatusCounter  uint64
+	beforeStatusCounter uint64
+	StatusMock          mPublisherMockStatus
 }
 
 // NewPublisherMock returns a mock for cloudmetrics.Publisher
@@ -29,6 +36,8 @@ func NewPublisherMock(t minimock.Tester) *PublisherMock {
 
 	m.PublishMock = mPublisherMockPublish{mock: m}
 
+	m.StatusMock = mPublisherMockStatus{mock: m}
+
 	return m
 }
 
@@ -167,10 +176,155 @@ func (m *PublisherMock) MinimockPublishInspect() {
 	}
 }
 
+type mPublisherMockStatus struct {
+	mock               *PublisherMock
+	defaultExpectation *PublisherMockStatusExpectation
+	expectations       []*PublisherMockStatusExpectation
+}
+
+// PublisherMockStatusExpectation specifies expectation struct of the Publisher.Status
+type PublisherMockStatusExpectation struct {
+	mock *PublisherMock
+
+	results *PublisherMockStatusResults
+	Counter uint64
+}
+
+// PublisherMockStatusResults contains results of the Publisher.Status
+type PublisherMockStatusResults struct {
+	s1 health.Status
+}
+
+// Expect sets up expected params for Publisher.Status
+func (mmStatus *mPublisherMockStatus) Expect() *mPublisherMockStatus {
+	if mmStatus.mock.funcStatus != nil {
+		mmStatus.mock.t.Fatalf("PublisherMock.Status mock is already set by Set")
+	}
+
+	if mmStatus.defaultExpectation == nil {
+		mmStatus.defaultExpectation = &PublisherMockStatusExpectation{}
+	}
+
+	return mmStatus
+}
+
+// Inspect accepts an inspector function that has same arguments as the Publisher.Status
+func (mmStatus *mPublisherMockStatus) Inspect(f func()) *mPublisherMockStatus {
+	if mmStatus.mock.inspectFuncStatus != nil {
+		mmStatus.mock.t.Fatalf("Inspect function is already set for PublisherMock.Status")
+	}
+
+	mmStatus.mock.inspectFuncStatus = f
+
+	return mmStatus
+}
+
+// Return sets up results that will be returned by Publisher.Status
+func (mmStatus *mPublisherMockStatus) Return(s1 health.Status) *PublisherMock {
+	if mmStatus.mock.funcStatus != nil {
+		mmStatus.mock.t.Fatalf("PublisherMock.Status mock is already set by Set")
+	}
+
+	if mmStatus.defaultExpectation == nil {
+		mmStatus.defaultExpectation = &PublisherMockStatusExpectation{mock: mmStatus.mock}
+	}
+	mmStatus.defaultExpectation.results = &PublisherMockStatusResults{s1}
+	return mmStatus.mock
+}
+
+//Set uses given function f to mock the Publisher.Status method
+func (mmStatus *mPublisherMockStatus) Set(f func() (s1 health.Status)) *PublisherMock {
+	if mmStatus.defaultExpectation != nil {
+		mmStatus.mock.t.Fatalf("Default expectation is already set for the Publisher.Status method")
+	}
+
+	if len(mmStatus.expectations) > 0 {
+		mmStatus.mock.t.Fatalf("Some expectations are already set for the Publisher.Status method")
+	}
+
+	mmStatus.mock.funcStatus = f
+	return mmStatus.mock
+}
+
+// Status implements cloudmetrics.Publisher
+func (mmStatus *PublisherMock) Status() (s1 health.Status) {
+	mm_atomic.AddUint64(&mmStatus.beforeStatusCounter, 1)
+	defer mm_atomic.AddUint64(&mmStatus.afterStatusCounter, 1)
+
+	if mmStatus.inspectFuncStatus != nil {
+		mmStatus.inspectFuncStatus()
+	}
+
+	if mmStatus.StatusMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmStatus.StatusMock.defaultExpectation.Counter, 1)
+
+		mm_results := mmStatus.StatusMock.defaultExpectation.results
+		if mm_results == nil {
+			mmStatus.t.Fatal("No results are set for the PublisherMock.Status")
+		}
+		return (*mm_results).s1
+	}
+	if mmStatus.funcStatus != nil {
+		return mmStatus.funcStatus()
+	}
+	mmStatus.t.Fatalf("Unexpected call to PublisherMock.Status.")
+	return
+}
+
+// StatusAfterCounter returns a count of finished PublisherMock.Status invocations
+func (mmStatus *PublisherMock) StatusAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmStatus.afterStatusCounter)
+}
+
+// StatusBeforeCounter returns a count of PublisherMock.Status invocations
+func (mmStatus *PublisherMock) StatusBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmStatus.beforeStatusCounter)
+}
+
+// MinimockStatusDone returns true if the count of the Status invocations corresponds
+// the number of defined expectations
+func (m *PublisherMock) MinimockStatusDone() bool {
+	for _, e := range m.StatusMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.StatusMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterStatusCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcStatus != nil && mm_atomic.LoadUint64(&m.afterStatusCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockStatusInspect logs each unmet expectation
+func (m *PublisherMock) MinimockStatusInspect() {
+	for _, e := range m.StatusMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Error("Expected call to PublisherMock.Status")
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.StatusMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterStatusCounter) < 1 {
+		m.t.Error("Expected call to PublisherMock.Status")
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcStatus != nil && mm_atomic.LoadUint64(&m.afterStatusCounter) < 1 {
+		m.t.Error("Expected call to PublisherMock.Status")
+	}
+}
+
 // MinimockFinish checks that all mocked methods have been called the expected number of times
 func (m *PublisherMock) MinimockFinish() {
 	if !m.minimockDone() {
 		m.MinimockPublishInspect()
+
+		m.MinimockStatusInspect()
 		m.t.FailNow()
 	}
 }
@@ -194,5 +348,6 @@ func (m *PublisherMock) MinimockWait(timeout mm_time.Duration) {
 func (m *PublisherMock) minimockDone() bool {
 	done := true
 	return done &&
-		m.MinimockPublishDone()
+		m.MinimockPublishDone() &&
+		m.MinimockStatusDone()
 }
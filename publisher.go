@@ -16,15 +16,17 @@ package cloudmetrics
 
 import (
 	"context"
+	"errors"
+	"math/rand"
 	"time"
 
 	awscloudmetrics "github.com/weareyolo/cloudmetrics/aws"
 	"github.com/weareyolo/cloudmetrics/datum"
+	"github.com/weareyolo/cloudmetrics/health"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/weareyolo/go-metrics"
-	"github.com/sirupsen/logrus"
 )
 
 // AWS limitation on `MetricData` length in `PutMetricDataInput`
@@ -32,116 +34,406 @@ const batchSize = 20
 
 type publisher struct {
 	ctx          context.Context
-	registry     metrics.Registry
+	sources      []*source
 	client       CloudWatch
 	interval     time.Duration
-	namespace    *string
-	logger       logrus.FieldLogger
-	datumBuilder DatumBuilder
+	logger       Logger
+	datumBuilder TimestampedDatumBuilder
+	sampleTick   time.Duration
+	self         *selfMetrics
+	status       *deliveryStatus
+	hooks        *hooks
+	typeBuilders *typeBuilders
+	router       *namespaceRouter
+	schedule     schedule
+	clock        Clock
+	suppressor   *suppressor
+	expirer      *expirer
+	unregister   bool
+	onInvalid    datum.InvalidPolicy
+	quarantine   *quarantine
+	deadLetters  DeadLetterHandler
+	deriver      *deriver
+	valueBuilder ValueDatumBuilder
+	resetSamples bool
+	unclearable  map[metricKey]bool
 }
 
-// NewPublisher creates a configured Publisher
+// NewPublisher creates a configured Publisher. Invalid settings are logged and ignored; use New
+// to have them reported as an error instead
 func NewPublisher(registry metrics.Registry, namespace string, opts ...Option) Publisher {
 	s := getSettings(opts)
 
+	if err := s.validate(); err != nil {
+		if s.Logger == nil {
+			s.Logger = newLogger()
+		}
+		s.Logger.WithError(err).Errorf("invalid settings, falling back to defaults")
+		s.dropInvalid()
+	}
+
+	return newPublisher(registry, namespace, s)
+}
+
+// New creates a configured Publisher, or returns an error when the settings are invalid
+func New(registry metrics.Registry, namespace string, opts ...Option) (Publisher, error) {
+	s := getSettings(opts)
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	return newPublisher(registry, namespace, s), nil
+}
+
+func newPublisher(registry metrics.Registry, namespace string, s *settings) *publisher {
 	b := s.DatumBuilder
 	if b == nil {
-		b = datum.NewBuilder(s.Units, s.Dimensions, s.Percentiles, s.StorageResolution)
+		b = datum.NewBuilder(s.Units, s.Dimensions, s.Percentiles, s.StorageResolution, s.builderOptions()...)
 	}
 
+	l := s.Logger
+	if l == nil {
+		l = newLogger()
+	}
+
+	clock := s.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	self := newSelfMetrics(s.SelfMetrics)
+
 	c := s.Client
 	if c == nil {
 		c = awscloudmetrics.NewCloudWatchClient()
 	}
+	if s.BreakerThreshold > 0 {
+		c = newCircuitBreaker(c, s.BreakerThreshold, s.BreakerOpenFor, clock, reportBreaker(l, self))
+	}
 
-	l := s.Logger
-	if l == nil {
-		l = newLogger()
+	h := &hooks{
+		batchSent:        s.BatchSentHooks,
+		batchError:       s.BatchErrorHooks,
+		intervalComplete: s.IntervalCompleteHooks,
+		metricExpired:    s.MetricExpiredHooks,
+		dispatch:         dispatchSync,
+		async:            s.AsyncHooks,
+		logger:           l,
 	}
 
-	return &publisher{
+	p := &publisher{
 		ctx:          s.Context,
-		registry:     registry,
-		namespace:    aws.String(namespace),
+		sources:      newSources(registry, namespace, s, self),
 		client:       c,
 		interval:     s.Interval,
 		logger:       l,
-		datumBuilder: b,
+		datumBuilder: timestamped(b),
+		sampleTick:   newSampler(s.ResolutionRules).tick,
+		self:         self,
+		status:       &deliveryStatus{status: health.Status{Interval: s.Interval}},
+		hooks:        h,
+		typeBuilders: newTypeBuilders(s.TypeBuilders),
+		router:       newNamespaceRouter(s.NamespaceRules),
+		schedule:     newSchedule(s.Interval, s.AlignInterval, s.StartJitter, rand.New(rand.NewSource(clock.Now().UnixNano()))),
+		clock:        clock,
+		suppressor:   newSuppressor(s.Suppressions),
+		expirer:      newExpirer(s.ExpireAfter, s.ResetSamples),
+		unregister:   s.UnregisterExpired,
+		onInvalid:    s.InvalidPolicy,
+		quarantine:   newQuarantine(s.Quarantine),
+		deadLetters:  s.DeadLetter,
+		deriver:      newDeriver(s.Derived, s.ResetSamples),
+		resetSamples: s.ResetSamples,
+		unclearable:  map[metricKey]bool{},
 	}
+	p.valueBuilder, _ = b.(ValueDatumBuilder)
+	return p
+}
+
+// newSources lists the registries to publish: the main one, the ones given with WithSources and
+// the self metrics registry when they are published
+func newSources(registry metrics.Registry, namespace string, s *settings, self *selfMetrics) []*source {
+	sources := []*source{}
+	if registry != nil {
+		sources = append(sources, newSource(Source{Registry: registry, Namespace: namespace}, s.ResolutionRules))
+	}
+	for _, src := range s.Sources {
+		sources = append(sources, newSource(src, s.ResolutionRules))
+	}
+
+	if s.PublishSelfMetrics {
+		for _, src := range sources {
+			if src.Registry == self.registry {
+				return sources
+			}
+		}
+
+		if registry == nil && len(sources) > 0 {
+			namespace = sources[0].Namespace
+		}
+		sources = append(sources, newSource(Source{Registry: self.registry, Namespace: namespace}, nil))
+	}
+
+	return sources
 }
 
 // Publish is the main entry point to publish metrics on a recurring basis to CloudWatch.
 func (p *publisher) Publish() {
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	defer p.hooks.start(p.ctx)()
+
+	next := p.schedule.first(p.clock.Now())
+	flushC := p.clock.After(next.Sub(p.clock.Now()))
+
+	// High resolution metrics are sampled on their own tick, between flushes
+	var sampleC <-chan time.Time
+	if p.sampleTick > 0 {
+		sampleC = p.clock.After(p.sampleTick)
+	}
 
 	for {
-		p.logger.Debugf("Waiting %v", p.interval)
-		// 1. Wait for either a tick or the context to close
+		p.logger.Debugf("Waiting until %v", next)
+		// 1. Wait for either a flush, a sample or the context to close
 		select {
 		case <-p.ctx.Done():
 			return
-		case <-ticker.C:
+		case now := <-sampleC:
+			p.sampleOnce(now)
+			sampleC = p.clock.After(p.sampleTick)
+			continue
+		case <-flushC:
 		}
 
-		p.publishMetrics(p.pollOnce())
+		p.flush(p.schedule.timestamp(next))
+
+		// A late flush makes the next one happen sooner, there is still one flush per interval
+		next = p.schedule.next(next)
+		flushC = p.clock.After(next.Sub(p.clock.Now()))
 	}
 }
 
-func (p *publisher) pollOnce() []*cloudwatch.MetricDatum {
-	p.logger.Debug("Polling metrics")
-	data := []*cloudwatch.MetricDatum{}
+// flush publishes the metrics of one interval
+func (p *publisher) flush(timestamp time.Time) {
+	start := p.clock.Now()
+	res := p.publishMetrics(p.sanitize(p.pollOnce(timestamp)))
+	res.Duration = p.clock.Now().Sub(start)
+	p.hooks.intervalDone(res)
+}
 
-	p.registry.Each(func(name string, i interface{}) {
-		switch v := i.(type) {
+// sampled returns the number of samples waiting for the next flush
+func (p *publisher) sampled() int {
+	n := 0
+	for _, src := range p.sources {
+		n += src.sampler.size
+	}
+	return n
+}
 
-		case metrics.Counter:
-			data = append(data, p.datumBuilder.BuildCounterData(v, name)...)
+func (p *publisher) sampleOnce(now time.Time) {
+	for _, src := range p.sources {
+		src.Registry.Each(func(name string, i interface{}) {
+			if src.sampler.due(name, now) {
+				src.sampler.add(name, now, src.decorate(p.buildData(name, i, now), now))
+			}
+		})
+	}
+	p.status.queued(p.sampled())
+}
 
-		case metrics.Gauge:
-			data = append(data, p.datumBuilder.BuildGaugeData(v, name)...)
+// pollOnce builds the datums of every registry, all stamped with timestamp or with the current
+// time when it is zero. Samples keep their own timestamp
+func (p *publisher) pollOnce(timestamp time.Time) namespacedData {
+	p.logger.Debugf("Polling metrics")
+	start := p.clock.Now()
+	defer func() { p.self.pollDuration.Update(p.clock.Now().Sub(start)) }()
+	if timestamp.IsZero() {
+		timestamp = start
+	}
+	data := namespacedData{}
+	expired := []ExpiredMetric{}
+	p.expirer.begin()
+	p.suppressor.begin()
+	p.deriver.begin()
 
-		case metrics.GaugeFloat64:
-			data = append(data, p.datumBuilder.BuildGaugeFloat64Data(v, name)...)
+	for _, src := range p.sources {
+		p.deriver.beginSource()
+		src.Registry.Each(func(name string, i interface{}) {
+			namespace := p.router.route(name, src.Namespace)
+			p.deriver.observe(src, name, i)
 
-		case metrics.Histogram:
-			data = append(data, p.datumBuilder.BuildHistogramData(v, name)...)
+			if skip, justExpired := p.observeExpiry(src, name, i, timestamp); skip {
+				src.sampler.drain(name, time.Time{})
+				if justExpired {
+					expired = append(expired, ExpiredMetric{
+						Registry:    src.Registry,
+						Namespace:   namespace,
+						Name:        name,
+						LastChanged: p.expirer.lastChanged(src, name),
+					})
+				}
+				return
+			}
 
-		case metrics.Meter:
-			data = append(data, p.datumBuilder.BuildMeterData(v, name)...)
+			// Sampled metrics send every sample taken since the last flush
+			if samples := src.sampler.drain(name, timestamp); len(samples) > 0 {
+				data.add(namespace, samples...)
+				p.reset(src, name, i)
+				return
+			}
 
-		case metrics.Timer:
-			data = append(data, p.datumBuilder.BuildTimerData(v, name)...)
+			built := p.buildData(name, i, timestamp)
+			p.reset(src, name, i)
+			if p.suppressor.skip(src, name, metricType(i), built) {
+				p.self.suppressed.Inc(int64(len(built)))
+				return
+			}
+			data.add(namespace, src.decorate(built, timestamp)...)
+		})
+		src.sampler.reset()
 
-		default:
-			p.logger.Errorf("Received unexpected metric: %#v", i)
-			return
+		// Derived metrics are routed by name before transformation, as the registered ones
+		for _, d := range p.buildDerived(timestamp) {
+			data.add(p.router.route(d.name, src.Namespace), src.decorate(d.data, timestamp)...)
 		}
-	})
+	}
+	p.expirer.sweep()
+	p.suppressor.sweep()
+	p.deriver.sweep()
+	p.expire(expired)
 
-	p.logger.Debugf("Received %v event(s)", len(data))
+	p.logger.Debugf("Received %v event(s)", data.len())
 	return data
 }
 
-func (p *publisher) publishMetrics(data []*cloudwatch.MetricDatum) {
-	for len(data) > batchSize {
-		if err := p.putMetrics(data[0:batchSize]); err != nil {
-			p.logger.WithError(err).Error("could not put chunk of metrics")
+// expire unregisters the metrics that just expired when configured to, and notifies the hooks.
+// Registries are modified once polled, not while being iterated
+func (p *publisher) expire(expired []ExpiredMetric) {
+	for _, m := range expired {
+		if p.unregister {
+			m.Registry.Unregister(m.Name)
+			m.Unregistered = true
+		}
+		p.logger.Infof("metric %s expired, unchanged since %v", m.Name, m.LastChanged)
+		p.hooks.expired(m)
+	}
+}
+
+func (p *publisher) buildData(name string, i interface{}, t time.Time) []*cloudwatch.MetricDatum {
+	if f, ok := p.typeBuilders.find(i); ok {
+		return f(i, name)
+	}
+
+	switch v := i.(type) {
+
+	case DatumProvider:
+		return v.Datums(name)
+
+	case metrics.Counter:
+		return p.datumBuilder.BuildCounterDataAt(v, name, t)
+
+	case metrics.Gauge:
+		return p.datumBuilder.BuildGaugeDataAt(v, name, t)
+
+	case metrics.GaugeFloat64:
+		return p.datumBuilder.BuildGaugeFloat64DataAt(v, name, t)
+
+	case metrics.Histogram:
+		return p.datumBuilder.BuildHistogramDataAt(v, name, t)
+
+	case metrics.Meter:
+		return p.datumBuilder.BuildMeterDataAt(v, name, t)
+
+	case metrics.Timer:
+		return p.datumBuilder.BuildTimerDataAt(v, name, t)
+
+	case metrics.Healthcheck:
+		// Refresh the status so that the datum does not report the outcome of an old check
+		v.Check()
+		return p.datumBuilder.BuildHealthcheckDataAt(v, name, t)
+
+	case metrics.EWMA:
+		return p.datumBuilder.BuildEWMADataAt(v, name, t)
+
+	default:
+		p.logger.Errorf("Received unexpected metric: %#v", i)
+		return nil
+	}
+}
+
+func (p *publisher) publishMetrics(data namespacedData) IntervalResult {
+	res := IntervalResult{Namespaces: data.namespaces()}
+	count := func(namespace string, batch []*cloudwatch.MetricDatum, failed []undelivered, err error) {
+		res.Batches++
+		res.Datums += len(batch)
+		if err != nil {
+			res.FailedBatches++
+			res.FailedDatums += p.undeliverable(namespace, failed, err)
 		}
-		data = data[batchSize:]
 	}
 
-	if len(data) > 0 {
-		if err := p.putMetrics(data); err != nil {
-			p.logger.WithError(err).Error("could not put last chunk of metrics")
+	queued := data.len()
+	p.status.queued(queued + p.sampled())
+	defer func() { p.status.queued(p.sampled()) }()
+
+	for _, namespace := range res.Namespaces {
+		nsData, quarantined := p.quarantine.filter(namespace, data[namespace], p.clock.Now())
+		if len(quarantined) > 0 {
+			p.self.quarantined.Inc(int64(len(quarantined)))
+			p.deadLetter(namespace, quarantined, ErrQuarantined, 0)
+			queued -= len(quarantined)
+		}
+
+		for len(nsData) > batchSize {
+			failed, err := p.sendBatch(namespace, nsData[0:batchSize], 1)
+			if err != nil && !errors.Is(err, ErrCircuitOpen) {
+				p.logger.WithError(err).Errorf("could not put chunk of metrics")
+			}
+			count(namespace, nsData[0:batchSize], failed, err)
+			nsData = nsData[batchSize:]
+			queued -= batchSize
+			p.status.queued(queued + p.sampled())
+		}
+
+		if len(nsData) > 0 {
+			failed, err := p.sendBatch(namespace, nsData, 1)
+			if err != nil && !errors.Is(err, ErrCircuitOpen) {
+				p.logger.WithError(err).Errorf("could not put last chunk of metrics")
+			}
+			count(namespace, nsData, failed, err)
+			queued -= len(nsData)
+			p.status.queued(queued + p.sampled())
 		}
 	}
+
+	return res
 }
 
-func (p *publisher) putMetrics(data []*cloudwatch.MetricDatum) error {
+func (p *publisher) putMetrics(namespace string, data []*cloudwatch.MetricDatum, attempt int) error {
+	start := p.clock.Now()
 	_, err := p.client.PutMetricData(&cloudwatch.PutMetricDataInput{
-		Namespace:  p.namespace,
+		Namespace:  aws.String(namespace),
 		MetricData: data,
 	})
+	if errors.Is(err, ErrCircuitOpen) {
+		// CloudWatch was not called, the breaker reports its own metrics
+		return err
+	}
+	duration := p.clock.Now().Sub(start)
+	p.self.putLatency.Update(duration)
+	p.status.attempted(start, err)
+
+	if err != nil {
+		p.self.batchFailed()
+	} else {
+		p.self.batchSent(len(data), p.clock.Now())
+	}
+
+	p.hooks.batchDone(BatchResult{
+		Namespace: namespace,
+		Batch:     data,
+		Attempt:   attempt,
+		Duration:  duration,
+		Err:       err,
+	})
 	return err
 }
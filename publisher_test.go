@@ -30,6 +30,7 @@ import (
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/datum"
 	"github.com/weareyolo/cloudmetrics/mock"
 )
 
@@ -44,79 +45,96 @@ func TestPublisher__Publish(t *testing.T) {
 	err := registry.Register("timer", timer)
 	require.NoError(t, err)
 
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
 	mockedDatum := &cloudwatch.MetricDatum{
 		MetricName: aws.String("timer"),
 		Value:      aws.Float64(1),
 		Unit:       aws.String(cloudwatch.StandardUnitCount),
 	}
+	// Datums are stamped with the snapshot timestamp of the interval
+	sentDatum := *mockedDatum
+	sentDatum.Timestamp = aws.Time(start.Add(time.Minute))
 
-	b := mock.NewDatumBuilderMock(mc)
-	b.BuildTimerDataMock.Expect(timer, "timer").Return([]*cloudwatch.MetricDatum{mockedDatum})
+	// publishOnce runs the publisher until its first flush is done
+	publishOnce := func(p Publisher, clock *manualClock, cancel context.CancelFunc) {
+		done := make(chan struct{})
+		go func() {
+			p.Publish()
+			close(done)
+		}()
+
+		clock.BlockUntil(1)
+		clock.Add(time.Minute)
+		// The next flush is scheduled once the first one is done
+		clock.BlockUntil(1)
+		cancel()
+		<-done
+	}
 
 	t.Run("OK - No error from CW", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		logger, _ := test.NewNullLogger()
+		clock := newManualClock(start)
+
+		b := mock.NewDatumBuilderMock(mc)
+		b.BuildTimerDataMock.Expect(timer, "timer").Return([]*cloudwatch.MetricDatum{mockedDatum})
 
 		cw := mock.NewCloudWatchMock(mc)
 		cw.PutMetricDataMock.Expect(&cloudwatch.PutMetricDataInput{
 			Namespace:  aws.String("nmsp"),
-			MetricData: []*cloudwatch.MetricDatum{mockedDatum},
+			MetricData: []*cloudwatch.MetricDatum{&sentDatum},
 		}).Return(nil, nil)
 
 		p := NewPublisher(registry, "nmsp",
 			WithClient(cw),
 			WithBuilder(b),
-			WithInterval(6*time.Millisecond),
+			WithInterval(time.Minute),
+			WithClock(clock),
 			WithContext(ctx),
 			WithLogger(logger),
 		)
 		require.NotNil(t, p)
 
-		go func() {
-			time.Sleep(10 * time.Millisecond)
-			cancel()
-		}()
-
-		p.Publish()
+		publishOnce(p, clock, cancel)
 
 		require.NotZero(t, b.BuildTimerDataAfterCounter())
 		require.NotZero(t, cw.PutMetricDataAfterCounter())
 
-		assert.InDelta(t, 1, b.BuildTimerDataAfterCounter(), 1)
-		assert.InDelta(t, 1, cw.PutMetricDataAfterCounter(), 1)
+		assert.EqualValues(t, 1, b.BuildTimerDataAfterCounter())
+		assert.EqualValues(t, 1, cw.PutMetricDataAfterCounter())
 	})
 
 	t.Run("OK - Error from CW is logged", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		logger, hook := test.NewNullLogger()
+		clock := newManualClock(start)
+
+		b := mock.NewDatumBuilderMock(mc)
+		b.BuildTimerDataMock.Expect(timer, "timer").Return([]*cloudwatch.MetricDatum{mockedDatum})
 
 		cw := mock.NewCloudWatchMock(mc)
 		cw.PutMetricDataMock.Expect(&cloudwatch.PutMetricDataInput{
 			Namespace:  aws.String("nmsp"),
-			MetricData: []*cloudwatch.MetricDatum{mockedDatum},
+			MetricData: []*cloudwatch.MetricDatum{&sentDatum},
 		}).Return(nil, errors.New("something happened"))
 
 		p := NewPublisher(registry, "nmsp",
 			WithClient(cw),
 			WithBuilder(b),
-			WithInterval(6*time.Millisecond),
+			WithInterval(time.Minute),
+			WithClock(clock),
 			WithContext(ctx),
 			WithLogger(logger),
 		)
 		require.NotNil(t, p)
 
-		go func() {
-			time.Sleep(10 * time.Millisecond)
-			cancel()
-		}()
-
-		p.Publish()
+		publishOnce(p, clock, cancel)
 
 		require.NotZero(t, b.BuildTimerDataAfterCounter())
 		require.NotZero(t, cw.PutMetricDataAfterCounter())
 
-		assert.InDelta(t, 1, b.BuildTimerDataAfterCounter(), 1)
-		assert.InDelta(t, 1, cw.PutMetricDataAfterCounter(), 1)
+		assert.EqualValues(t, 1, b.BuildTimerDataAfterCounter())
+		assert.EqualValues(t, 1, cw.PutMetricDataAfterCounter())
 
 		require.Len(t, hook.Entries, 1)
 		entry := hook.Entries[0]
@@ -125,3 +143,134 @@ func TestPublisher__Publish(t *testing.T) {
 		assert.EqualError(t, entry.Data[logrus.ErrorKey].(error), "something happened")
 	})
 }
+
+func TestNew(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	cw := mock.NewCloudWatchMock(mc)
+	registry := metrics.NewRegistry()
+
+	t.Run("OK - Valid units", func(t *testing.T) {
+		p, err := New(registry, "nmsp",
+			WithClient(cw),
+			WithUnits(map[string]string{"size": cloudwatch.StandardUnitBytes}),
+			WithUnitRules(datum.SuffixUnit(".ms", cloudwatch.StandardUnitMilliseconds)),
+		)
+		require.NoError(t, err)
+		assert.NotNil(t, p)
+	})
+
+	t.Run("NOK - Invalid units", func(t *testing.T) {
+		p, err := New(registry, "nmsp",
+			WithClient(cw),
+			WithUnits(map[string]string{"size": "bytes"}),
+		)
+		assert.EqualError(t, err, `invalid unit(s): "bytes" for metric "size"`)
+		assert.Nil(t, p)
+	})
+}
+
+func TestNewPublisher__InvalidUnits(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	cw := mock.NewCloudWatchMock(mc)
+	logger, hook := test.NewNullLogger()
+
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(cw),
+		WithLogger(logger),
+		WithUnits(map[string]string{"size": "bytes", "latency": cloudwatch.StandardUnitSeconds}),
+		WithUnitRules(datum.PrefixUnit("db.", "Secs")),
+	)
+	require.NotNil(t, p)
+
+	require.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.ErrorLevel, hook.Entries[0].Level)
+	assert.Equal(t, "invalid settings, falling back to defaults", hook.Entries[0].Message)
+
+	b := p.(*publisher).datumBuilder
+	timer := metrics.NewTimer()
+	timer.Update(time.Second)
+
+	data := b.BuildTimerData(timer, "size")
+	require.Len(t, data, 5)
+	assert.Equal(t, cloudwatch.StandardUnitMilliseconds, *data[1].Unit)
+
+	data = b.BuildTimerData(timer, "db.query")
+	require.Len(t, data, 5)
+	assert.Equal(t, cloudwatch.StandardUnitMilliseconds, *data[1].Unit)
+
+	data = b.BuildTimerData(timer, "latency")
+	require.Len(t, data, 5)
+	assert.Equal(t, cloudwatch.StandardUnitSeconds, *data[1].Unit)
+}
+
+func TestPublisher__PollOnce(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	checks := 0
+	healthcheck := metrics.NewHealthcheck(func(h metrics.Healthcheck) {
+		checks++
+		h.Healthy()
+	})
+	ewma := metrics.NewEWMA1()
+	gauge := metrics.NewFunctionalGauge(func() int64 { return 1 })
+	gaugeFloat64 := metrics.NewFunctionalGaugeFloat64(func() float64 { return 1.5 })
+
+	// StandardRegistry silently ignores EWMAs, custom registries may hold them
+	registry := &staticRegistry{
+		Registry: metrics.NewRegistry(),
+		metrics: map[string]interface{}{
+			"healthcheck":   healthcheck,
+			"ewma":          ewma,
+			"gauge":         gauge,
+			"gauge-float64": gaugeFloat64,
+		},
+	}
+
+	datumFor := func(name string) []*cloudwatch.MetricDatum {
+		return []*cloudwatch.MetricDatum{{MetricName: aws.String(name)}}
+	}
+
+	b := mock.NewDatumBuilderMock(mc)
+	b.BuildHealthcheckDataMock.Expect(healthcheck, "healthcheck").Return(datumFor("healthcheck"))
+	b.BuildEWMADataMock.Expect(ewma, "ewma").Return(datumFor("ewma"))
+	b.BuildGaugeDataMock.Expect(gauge, "gauge").Return(datumFor("gauge"))
+	b.BuildGaugeFloat64DataMock.Expect(gaugeFloat64, "gauge-float64").Return(datumFor("gauge-float64"))
+
+	logger, hook := test.NewNullLogger()
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithBuilder(b),
+		WithLogger(logger),
+	).(*publisher)
+
+	data := flatten(p.pollOnce(time.Time{}))
+	assert.Len(t, data, 4)
+	assert.Equal(t, 1, checks)
+	assert.Empty(t, hook.Entries)
+}
+
+// flatten returns the datums of every namespace
+func flatten(data namespacedData) []*cloudwatch.MetricDatum {
+	res := []*cloudwatch.MetricDatum{}
+	for _, ns := range data.namespaces() {
+		res = append(res, data[ns]...)
+	}
+	return res
+}
+
+// staticRegistry is a metrics.Registry iterating over a fixed set of metrics of any type
+type staticRegistry struct {
+	metrics.Registry
+	metrics map[string]interface{}
+}
+
+func (r *staticRegistry) Each(f func(string, interface{})) {
+	for name, i := range r.metrics {
+		f(name, i)
+	}
+}
@@ -0,0 +1,125 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// isInvalidBatch reports whether err is CloudWatch rejecting the content of a batch. Such a
+// batch fails the same way every time it is sent
+func isInvalidBatch(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case cloudwatch.ErrCodeInvalidParameterValueException,
+		cloudwatch.ErrCodeInvalidParameterCombinationException,
+		cloudwatch.ErrCodeMissingRequiredParameterException:
+		return true
+	}
+	return false
+}
+
+type quarantineKey struct {
+	namespace string
+	name      string
+}
+
+// ErrQuarantined is the error given to the dead-letter handler for the datums of quarantined
+// metrics, which are not sent
+var ErrQuarantined = errors.New("metric is quarantined")
+
+// quarantine holds the metric names rejected by CloudWatch, which are not sent again until
+// their cooldown is over
+type quarantine struct {
+	cooldown time.Duration
+	until    map[quarantineKey]time.Time
+}
+
+func newQuarantine(cooldown time.Duration) *quarantine {
+	return &quarantine{
+		cooldown: cooldown,
+		until:    map[quarantineKey]time.Time{},
+	}
+}
+
+func (q *quarantine) add(namespace, name string, now time.Time) {
+	if q.cooldown > 0 {
+		q.until[quarantineKey{namespace: namespace, name: name}] = now.Add(q.cooldown)
+	}
+}
+
+// filter separates the quarantined datums from the others
+func (q *quarantine) filter(namespace string, data []*cloudwatch.MetricDatum, now time.Time) (kept, quarantined []*cloudwatch.MetricDatum) {
+	if len(q.until) == 0 {
+		return data, nil
+	}
+
+	kept = make([]*cloudwatch.MetricDatum, 0, len(data))
+	for _, d := range data {
+		key := quarantineKey{namespace: namespace, name: aws.StringValue(d.MetricName)}
+		until, ok := q.until[key]
+		if ok && now.Before(until) {
+			quarantined = append(quarantined, d)
+			continue
+		}
+		if ok {
+			delete(q.until, key)
+		}
+		kept = append(kept, d)
+	}
+	return kept, quarantined
+}
+
+// sendBatch puts a batch of datums. When CloudWatch rejects its content, the batch is split in
+// halves until the offending datums are found: the others are delivered and the offending metric
+// names quarantined. attempt counts the calls made with these datums so far, including this one.
+// It returns the datums that could not be delivered, with the number of calls made for them
+func (p *publisher) sendBatch(namespace string, batch []*cloudwatch.MetricDatum, attempt int) ([]undelivered, error) {
+	err := p.putMetrics(namespace, batch, attempt)
+	if err == nil {
+		return nil, nil
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		// CloudWatch was not called
+		return []undelivered{{datums: batch, attempts: attempt - 1}}, err
+	}
+	if !isInvalidBatch(err) {
+		return []undelivered{{datums: batch, attempts: attempt}}, err
+	}
+
+	if len(batch) == 1 {
+		name := aws.StringValue(batch[0].MetricName)
+		p.logger.WithField("metric", name).WithError(err).Warnf("metric rejected by CloudWatch, quarantined for %v", p.quarantine.cooldown)
+		p.quarantine.add(namespace, name, p.clock.Now())
+		return []undelivered{{datums: batch, attempts: attempt}}, err
+	}
+
+	mid := len(batch) / 2
+	failedLeft, errLeft := p.sendBatch(namespace, batch[:mid], attempt+1)
+	failedRight, errRight := p.sendBatch(namespace, batch[mid:], attempt+1)
+	if errLeft == nil {
+		errLeft = errRight
+	}
+	return append(failedLeft, failedRight...), errLeft
+}
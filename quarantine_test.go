@@ -0,0 +1,123 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestIsInvalidBatch(t *testing.T) {
+	assert.True(t, isInvalidBatch(awserr.New(cloudwatch.ErrCodeInvalidParameterValueException, "bad", nil)))
+	assert.True(t, isInvalidBatch(fmt.Errorf("wrapped: %w",
+		awserr.New(cloudwatch.ErrCodeMissingRequiredParameterException, "bad", nil))))
+	assert.False(t, isInvalidBatch(awserr.New("Throttling", "slow down", nil)))
+	assert.False(t, isInvalidBatch(errors.New("something happened")))
+}
+
+func TestPublisher__Bisect(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	data := []*cloudwatch.MetricDatum{}
+	for _, name := range []string{"a", "b", "bad", "c", "d"} {
+		data = append(data, &cloudwatch.MetricDatum{MetricName: aws.String(name), Value: aws.Float64(1)})
+	}
+
+	// CloudWatch rejects every batch holding the bad datum
+	delivered := []string{}
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		for _, d := range input.MetricData {
+			if *d.MetricName == "bad" {
+				return nil, awserr.New(cloudwatch.ErrCodeInvalidParameterValueException, "bad datum", nil)
+			}
+		}
+		for _, d := range input.MetricData {
+			delivered = append(delivered, *d.MetricName)
+		}
+		return nil, nil
+	})
+
+	clock := newManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	selfRegistry := metrics.NewRegistry()
+	logger, hook := test.NewNullLogger()
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(cw),
+		WithClock(clock),
+		WithLogger(logger),
+		WithSelfMetrics(selfRegistry),
+		WithQuarantine(5*time.Minute),
+	).(*publisher)
+	counter := func(name string) int64 {
+		return selfRegistry.Get(SelfMetricsPrefix + name).(metrics.Counter).Count()
+	}
+
+	t.Run("OK - Valid datums are delivered", func(t *testing.T) {
+		res := p.publishMetrics(namespacedData{"nmsp": data})
+		assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, delivered)
+		assert.Equal(t, 1, res.FailedBatches)
+		assert.Equal(t, 1, res.FailedDatums)
+		assert.Equal(t, int64(1), counter("datums.dropped"))
+
+		require.NotEmpty(t, hook.Entries)
+		assert.Equal(t, "metric rejected by CloudWatch, quarantined for 5m0s", hook.Entries[0].Message)
+		assert.Equal(t, "bad", hook.Entries[0].Data["metric"])
+	})
+
+	t.Run("OK - Rejected metrics are quarantined", func(t *testing.T) {
+		delivered = delivered[:0]
+		calls := cw.PutMetricDataAfterCounter()
+
+		res := p.publishMetrics(namespacedData{"nmsp": data})
+		assert.Equal(t, []string{"a", "b", "c", "d"}, delivered)
+		assert.Equal(t, calls+1, cw.PutMetricDataAfterCounter())
+		assert.Zero(t, res.FailedDatums)
+		assert.Equal(t, int64(1), counter("datums.quarantined"))
+	})
+
+	t.Run("OK - Quarantine is over", func(t *testing.T) {
+		delivered = delivered[:0]
+		clock.Add(5 * time.Minute)
+
+		res := p.publishMetrics(namespacedData{"nmsp": data})
+		assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, delivered)
+		assert.Equal(t, 1, res.FailedDatums)
+		assert.Len(t, p.quarantine.until, 1)
+	})
+
+	t.Run("OK - Other errors are not bisected", func(t *testing.T) {
+		cw := mock.NewCloudWatchMock(mc)
+		cw.PutMetricDataMock.Return(nil, awserr.New("Throttling", "slow down", nil))
+		p := NewPublisher(metrics.NewRegistry(), "nmsp", WithClient(cw), WithLogger(logger)).(*publisher)
+
+		res := p.publishMetrics(namespacedData{"nmsp": data})
+		assert.EqualValues(t, 1, cw.PutMetricDataAfterCounter())
+		assert.Equal(t, 5, res.FailedDatums)
+		assert.Empty(t, p.quarantine.until)
+	})
+}
@@ -0,0 +1,86 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"github.com/weareyolo/go-metrics"
+)
+
+// clearable is implemented by the histograms and timers able to drop their samples
+type clearable interface {
+	Clear()
+}
+
+// ResettableTimer is a metrics.Timer able to drop its samples. The timers of go-metrics cannot be
+// cleared, register ResettableTimers instead for WithIntervalReset to apply to timers
+type ResettableTimer struct {
+	metrics.Timer
+	histogram metrics.Histogram
+}
+
+// NewResettableTimer creates a ResettableTimer backed by an exponentially decaying sample, as
+// metrics.NewTimer
+func NewResettableTimer() *ResettableTimer {
+	h := metrics.NewHistogram(metrics.NewExpDecaySample())
+	return &ResettableTimer{
+		Timer:     metrics.NewCustomTimer(h, metrics.NewMeter()),
+		histogram: h,
+	}
+}
+
+// Clear drops the samples of the timer. Its rates are kept
+func (t *ResettableTimer) Clear() {
+	t.histogram.Clear()
+}
+
+// clearedOnFlush reports whether WithIntervalReset clears the samples of a metric, leaving counts
+// local to each interval: histograms, and the timers implementing Clear()
+func clearedOnFlush(i interface{}) bool {
+	switch i.(type) {
+	case metrics.Histogram:
+		return true
+	case metrics.Timer:
+		_, ok := i.(clearable)
+		return ok
+	default:
+		return false
+	}
+}
+
+// reset clears the samples of a histogram or timer once its data is built, when configured to.
+// Timers which cannot be cleared are reported once
+func (p *publisher) reset(src *source, name string, i interface{}) {
+	if !p.resetSamples {
+		return
+	}
+
+	switch i.(type) {
+	case metrics.Histogram, metrics.Timer:
+	default:
+		return
+	}
+
+	if c, ok := i.(clearable); ok {
+		c.Clear()
+		return
+	}
+
+	key := metricKey{src: src, name: name}
+	if p.unclearable[key] || src.Registry == p.self.registry {
+		return
+	}
+	p.unclearable[key] = true
+	p.logger.Warnf("timer %s cannot be cleared, its data spans every interval; use NewResettableTimer", name)
+}
@@ -0,0 +1,149 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestResettableTimer(t *testing.T) {
+	timer := NewResettableTimer()
+	timer.Update(time.Second)
+	timer.Update(time.Second)
+	require.EqualValues(t, 2, timer.Count())
+	assert.EqualValues(t, 2, timer.Snapshot().Count())
+
+	timer.Clear()
+	assert.Zero(t, timer.Count())
+	assert.Zero(t, timer.Snapshot().Percentile(.5))
+}
+
+func TestPublisher__IntervalReset(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	histogram := metrics.NewHistogram(metrics.NewUniformSample(10))
+	resettable := NewResettableTimer()
+	timer := metrics.NewTimer()
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("histogram", histogram))
+	require.NoError(t, registry.Register("resettable", resettable))
+	require.NoError(t, registry.Register("timer", timer))
+
+	update := func() {
+		histogram.Update(100)
+		resettable.Update(time.Second)
+		timer.Update(time.Second)
+	}
+	names := func(data namespacedData) []string {
+		res := []string{}
+		for _, d := range flatten(data) {
+			res = append(res, *d.MetricName)
+		}
+		return res
+	}
+
+	t.Run("OK - Samples kept by default", func(t *testing.T) {
+		p := NewPublisher(registry, "nmsp", WithClient(mock.NewCloudWatchMock(mc))).(*publisher)
+
+		update()
+		p.pollOnce(time.Time{})
+		assert.EqualValues(t, 1, histogram.Count())
+		assert.EqualValues(t, 1, resettable.Count())
+		assert.EqualValues(t, 1, timer.Count())
+	})
+
+	t.Run("OK - Samples cleared once built", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithLogger(logger),
+			WithPercentiles([]float64{.5}),
+			WithIntervalReset(),
+		).(*publisher)
+
+		update()
+		assert.ElementsMatch(t, []string{
+			"histogram.count", "histogram.p50",
+			"resettable.count", "resettable.p50",
+			"timer.count", "timer.p50",
+		}, names(p.pollOnce(time.Time{})))
+		assert.Zero(t, histogram.Count())
+		assert.Zero(t, resettable.Count())
+		assert.EqualValues(t, 2, timer.Count())
+
+		// Nothing was observed during the interval, go-metrics timers cannot be cleared
+		assert.ElementsMatch(t, []string{"timer.count", "timer.p50"}, names(p.pollOnce(time.Time{})))
+
+		require.Len(t, hook.Entries, 1)
+		assert.Equal(t, "timer timer cannot be cleared, its data spans every interval; use NewResettableTimer",
+			hook.Entries[0].Message)
+	})
+}
+
+func TestPublisher__IntervalResetCounts(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	histogram := metrics.NewHistogram(metrics.NewUniformSample(100))
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("h", histogram))
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithIntervalReset(),
+		WithExpiry(2, true),
+		WithDerived(Derived{Name: "h.updates", Expr: Metric("h")}),
+	).(*publisher)
+
+	derived := func() (float64, bool) {
+		for _, d := range flatten(p.pollOnce(time.Time{})) {
+			if *d.MetricName == "h.updates" {
+				return *d.Value, true
+			}
+		}
+		return 0, false
+	}
+
+	t.Run("OK - Counts of each interval", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 10; j++ {
+				histogram.Update(int64(j))
+			}
+			v, ok := derived()
+			require.True(t, ok, "poll %d", i)
+			assert.Equal(t, 10.0, v, "poll %d", i)
+			assert.NotNil(t, registry.Get("h"), "poll %d", i)
+		}
+	})
+
+	t.Run("OK - Idle intervals expire", func(t *testing.T) {
+		v, ok := derived()
+		require.True(t, ok)
+		assert.Zero(t, v)
+		assert.NotNil(t, registry.Get("h"))
+
+		derived()
+		assert.Nil(t, registry.Get("h"))
+	})
+}
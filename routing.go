@@ -0,0 +1,59 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+
+	"github.com/weareyolo/cloudmetrics/datum"
+)
+
+// NamespaceRule publishes the metrics whose name matches the rule to Namespace instead of the
+// namespace of their registry. Precedence among matching rules follows datum.Matcher
+type NamespaceRule struct {
+	datum.Matcher
+	Namespace string
+}
+
+// PrefixNamespace creates a NamespaceRule matching metric names starting with prefix
+func PrefixNamespace(prefix string, namespace string) NamespaceRule {
+	return NamespaceRule{Matcher: datum.MatchPrefix(prefix), Namespace: namespace}
+}
+
+// RegexpNamespace creates a NamespaceRule matching metric names matched by pattern
+func RegexpNamespace(pattern *regexp.Regexp, namespace string) NamespaceRule {
+	return NamespaceRule{Matcher: datum.MatchRegexp(pattern), Namespace: namespace}
+}
+
+type namespaceRouter struct {
+	rules []NamespaceRule
+}
+
+func newNamespaceRouter(rules []NamespaceRule) *namespaceRouter {
+	sorted := make([]NamespaceRule, len(rules))
+	copy(sorted, rules)
+	datum.SortByPriority(sorted, func(i int) datum.Matcher { return sorted[i].Matcher })
+	return &namespaceRouter{rules: sorted}
+}
+
+// route returns the namespace of the metric, defaulting to the one of its registry
+func (r *namespaceRouter) route(name string, defaultNamespace string) string {
+	for _, rule := range r.rules {
+		if rule.Matches(name) {
+			return rule.Namespace
+		}
+	}
+	return defaultNamespace
+}
@@ -0,0 +1,71 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestNamespaceRouter(t *testing.T) {
+	r := newNamespaceRouter([]NamespaceRule{
+		RegexpNamespace(regexp.MustCompile(`^http\.`), "Platform/Regexp"),
+		PrefixNamespace("http.", "Platform/HTTP"),
+		PrefixNamespace("http.api.", "Platform/API"),
+	})
+
+	assert.Equal(t, "Platform/HTTP", r.route("http.api.requests", "service"))
+	assert.Equal(t, "Platform/HTTP", r.route("http.requests", "service"))
+	assert.Equal(t, "service", r.route("db.queries", "service"))
+}
+
+func TestPublisher__NamespaceRules(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("http.requests", metrics.NewCounter()))
+	require.NoError(t, registry.Register("http.errors", metrics.NewCounter()))
+	require.NoError(t, registry.Register("jobs", metrics.NewCounter()))
+
+	p := NewPublisher(registry, "service",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithNamespaceRules(PrefixNamespace("http.", "Platform/HTTP")),
+	).(*publisher)
+
+	data := p.pollOnce(time.Time{})
+	assert.Equal(t, []string{"Platform/HTTP", "service"}, data.namespaces())
+	assert.Len(t, data["Platform/HTTP"], 2)
+	require.Len(t, data["service"], 1)
+	assert.Equal(t, "jobs", *data["service"][0].MetricName)
+}
+
+func TestNew__InvalidNamespaceRule(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	_, err := New(metrics.NewRegistry(), "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithNamespaceRules(PrefixNamespace("http.", "")),
+	)
+	require.EqualError(t, err, `empty namespace for prefix "http."`)
+}
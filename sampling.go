@@ -0,0 +1,177 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/cloudmetrics/datum"
+)
+
+// sampler keeps the samples taken between two flushes for the metrics whose resolution rule
+// has a SampleInterval. Samples of aggregated rules are folded into statistics as they are taken
+type sampler struct {
+	rules      []datum.ResolutionRule
+	tick       time.Duration
+	last       map[string]time.Time
+	samples    map[string][]*cloudwatch.MetricDatum
+	aggregates map[string][]*aggregate
+	drained    map[string]bool
+	size       int
+}
+
+func newSampler(rules []datum.ResolutionRule) *sampler {
+	sorted := datum.SortResolutionRules(rules)
+
+	var tick time.Duration
+	for _, r := range sorted {
+		if r.SampleInterval > 0 && (tick == 0 || r.SampleInterval < tick) {
+			tick = r.SampleInterval
+		}
+	}
+
+	return &sampler{
+		rules:      sorted,
+		tick:       tick,
+		last:       map[string]time.Time{},
+		samples:    map[string][]*cloudwatch.MetricDatum{},
+		aggregates: map[string][]*aggregate{},
+		drained:    map[string]bool{},
+	}
+}
+
+// interval returns the sample interval of the metric, or 0 when it is not sampled
+func (s *sampler) interval(name string) time.Duration {
+	r, ok := datum.FindResolutionRule(s.rules, name)
+	if !ok {
+		return 0
+	}
+	return r.SampleInterval
+}
+
+// due reports whether the metric must be sampled at now. Half a tick of tolerance absorbs the
+// ticker drift so that a metric sampled on every n-th tick is not pushed to the next one
+func (s *sampler) due(name string, now time.Time) bool {
+	i := s.interval(name)
+	if i == 0 {
+		return false
+	}
+
+	last, ok := s.last[name]
+	return !ok || now.Sub(last)+s.tick/2 >= i
+}
+
+func (s *sampler) add(name string, now time.Time, data []*cloudwatch.MetricDatum) {
+	s.last[name] = now
+
+	if r, ok := datum.FindResolutionRule(s.rules, name); ok && r.Aggregate {
+		for _, d := range data {
+			s.aggregate(name, d)
+		}
+		return
+	}
+
+	s.samples[name] = append(s.samples[name], data...)
+	s.size += len(data)
+}
+
+// aggregate folds a sample into the statistics of its datum name
+func (s *sampler) aggregate(name string, d *cloudwatch.MetricDatum) {
+	if d.Value == nil {
+		return
+	}
+
+	for _, a := range s.aggregates[name] {
+		if aws.StringValue(a.template.MetricName) == aws.StringValue(d.MetricName) {
+			a.add(*d.Value)
+			return
+		}
+	}
+
+	a := &aggregate{template: d}
+	a.add(*d.Value)
+	s.aggregates[name] = append(s.aggregates[name], a)
+	s.size++
+}
+
+// drain returns the samples taken for the metric since the last flush and forgets them.
+// Statistics of aggregated samples are stamped with t
+func (s *sampler) drain(name string, t time.Time) []*cloudwatch.MetricDatum {
+	data := s.samples[name]
+	for _, a := range s.aggregates[name] {
+		data = append(data, a.datum(t))
+	}
+
+	delete(s.samples, name)
+	delete(s.aggregates, name)
+	s.drained[name] = true
+	s.size -= len(data)
+	return data
+}
+
+// reset forgets the samples and sampling times left over by metrics no longer in the registry,
+// which were not drained on flush
+func (s *sampler) reset() {
+	for name := range s.last {
+		if !s.drained[name] {
+			delete(s.last, name)
+		}
+	}
+	s.samples = map[string][]*cloudwatch.MetricDatum{}
+	s.aggregates = map[string][]*aggregate{}
+	s.drained = map[string]bool{}
+	s.size = 0
+}
+
+// aggregate holds the statistics of the samples of a datum
+type aggregate struct {
+	template *cloudwatch.MetricDatum
+	min      float64
+	max      float64
+	sum      float64
+	count    float64
+}
+
+func (a *aggregate) add(v float64) {
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.sum += v
+	a.count++
+}
+
+// datum returns a StatisticSet datum with the name, unit, dimensions and storage resolution of
+// the samples
+func (a *aggregate) datum(t time.Time) *cloudwatch.MetricDatum {
+	d := *a.template
+	d.Value = nil
+	d.Values = nil
+	d.Counts = nil
+	d.StatisticValues = &cloudwatch.StatisticSet{
+		Minimum:     aws.Float64(a.min),
+		Maximum:     aws.Float64(a.max),
+		Sum:         aws.Float64(a.sum),
+		SampleCount: aws.Float64(a.count),
+	}
+	if !t.IsZero() {
+		d.Timestamp = aws.Time(t.UTC())
+	}
+	return &d
+}
@@ -0,0 +1,181 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/datum"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestSampler(t *testing.T) {
+	s := newSampler([]datum.ResolutionRule{
+		datum.PrefixResolution("fast.", datum.HighResolution).SampledEvery(2 * time.Second),
+		datum.PrefixResolution("faster.", datum.HighResolution).SampledEvery(time.Second),
+		datum.PrefixResolution("hr.", datum.HighResolution),
+	})
+	assert.Equal(t, time.Second, s.tick)
+
+	t.Run("OK - Not sampled", func(t *testing.T) {
+		assert.Zero(t, s.interval("hr.metric"))
+		assert.False(t, s.due("hr.metric", time.Now()))
+		assert.False(t, s.due("other", time.Now()))
+	})
+
+	t.Run("OK - Due every interval", func(t *testing.T) {
+		now := time.Now()
+		assert.True(t, s.due("fast.metric", now))
+		s.add("fast.metric", now, []*cloudwatch.MetricDatum{{MetricName: aws.String("fast.metric")}})
+
+		assert.False(t, s.due("fast.metric", now.Add(time.Second)))
+		assert.True(t, s.due("fast.metric", now.Add(1990*time.Millisecond)))
+		s.add("fast.metric", now, []*cloudwatch.MetricDatum{{MetricName: aws.String("fast.metric")}})
+
+		assert.Len(t, s.drain("fast.metric", time.Time{}), 2)
+		assert.Empty(t, s.drain("fast.metric", time.Time{}))
+	})
+
+	t.Run("OK - Reset", func(t *testing.T) {
+		s.add("faster.metric", time.Now(), []*cloudwatch.MetricDatum{{MetricName: aws.String("faster.metric")}})
+		s.reset()
+		assert.Empty(t, s.drain("faster.metric", time.Time{}))
+
+		// Only the metrics drained on flush, still in the registry, keep their sampling time
+		assert.Contains(t, s.last, "fast.metric")
+		assert.NotContains(t, s.last, "faster.metric")
+		s.reset()
+		assert.Empty(t, s.last)
+	})
+}
+
+func TestPublisher__Sampling(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	fast := metrics.NewGauge()
+	slow := metrics.NewGauge()
+	require.NoError(t, registry.Register("fast", fast))
+	require.NoError(t, registry.Register("slow", slow))
+
+	logger, _ := test.NewNullLogger()
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithLogger(logger),
+		WithResolutionRules(datum.NameResolution("fast", datum.HighResolution).SampledEvery(time.Second)),
+	).(*publisher)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		fast.Update(int64(i))
+		slow.Update(int64(i))
+		p.sampleOnce(now.Add(time.Duration(i) * time.Second))
+	}
+
+	assert.Equal(t, 3, p.Status().QueueDepth)
+
+	data := flatten(p.pollOnce(time.Time{}))
+	require.Len(t, data, 4)
+
+	values := map[string][]float64{}
+	for _, d := range data {
+		values[*d.MetricName] = append(values[*d.MetricName], *d.Value)
+		if *d.MetricName == "fast" {
+			assert.Equal(t, datum.HighResolution, *d.StorageResolution)
+		} else {
+			assert.Equal(t, datum.StandardResolution, *d.StorageResolution)
+		}
+	}
+	assert.Equal(t, []float64{0, 1, 2}, values["fast"])
+	assert.Equal(t, []float64{2}, values["slow"])
+
+	// Without samples, the flush builds a single datum
+	data = flatten(p.pollOnce(time.Time{}))
+	require.Len(t, data, 2)
+}
+
+func TestSampler__Aggregate(t *testing.T) {
+	s := newSampler([]datum.ResolutionRule{
+		datum.NameResolution("queue.depth", datum.StandardResolution).SampledEvery(time.Second).Aggregated(),
+	})
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []float64{3, 12, 1, 4} {
+		s.add("queue.depth", now.Add(time.Duration(i)*time.Second), []*cloudwatch.MetricDatum{{
+			MetricName:        aws.String("queue.depth"),
+			Value:             aws.Float64(v),
+			Unit:              aws.String(cloudwatch.StandardUnitCount),
+			StorageResolution: aws.Int64(datum.StandardResolution),
+			Timestamp:         aws.Time(now.Add(time.Duration(i) * time.Second)),
+		}})
+	}
+	assert.Equal(t, 1, s.size)
+
+	flush := now.Add(time.Minute)
+	data := s.drain("queue.depth", flush)
+	require.Len(t, data, 1)
+	assert.Equal(t, &cloudwatch.MetricDatum{
+		MetricName: aws.String("queue.depth"),
+		StatisticValues: &cloudwatch.StatisticSet{
+			Minimum:     aws.Float64(1),
+			Maximum:     aws.Float64(12),
+			Sum:         aws.Float64(20),
+			SampleCount: aws.Float64(4),
+		},
+		Unit:              aws.String(cloudwatch.StandardUnitCount),
+		StorageResolution: aws.Int64(datum.StandardResolution),
+		Timestamp:         aws.Time(flush),
+	}, data[0])
+	assert.Zero(t, s.size)
+	assert.Empty(t, s.drain("queue.depth", flush))
+}
+
+func TestPublisher__AggregatedSampling(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	depth := metrics.NewGauge()
+	require.NoError(t, registry.Register("queue.depth", depth))
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithResolutionRules(datum.NameResolution("queue.depth", datum.StandardResolution).SampledEvery(time.Second).Aggregated()),
+	).(*publisher)
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, v := range []int64{2, 40, 5} {
+		depth.Update(v)
+		p.sampleOnce(now.Add(time.Duration(i) * time.Second))
+	}
+	assert.Equal(t, 1, p.Status().QueueDepth)
+
+	flush := now.Add(time.Minute)
+	data := flatten(p.pollOnce(flush))
+	require.Len(t, data, 1)
+	assert.Nil(t, data[0].Value)
+	assert.Equal(t, 40.0, *data[0].StatisticValues.Maximum)
+	assert.Equal(t, 3.0, *data[0].StatisticValues.SampleCount)
+	assert.Equal(t, flush, *data[0].Timestamp)
+}
@@ -0,0 +1,69 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"math/rand"
+	"time"
+)
+
+// schedule computes when the intervals are flushed
+type schedule struct {
+	interval time.Duration
+	align    bool
+	offset   time.Duration
+}
+
+// newSchedule creates a schedule whose flushes are delayed by a random offset in [0, jitter),
+// picked once so that there is still exactly one flush per interval
+func newSchedule(interval time.Duration, align bool, jitter time.Duration, rnd *rand.Rand) schedule {
+	if jitter > interval {
+		jitter = interval
+	}
+
+	var offset time.Duration
+	if jitter > 0 {
+		offset = time.Duration(rnd.Int63n(int64(jitter)))
+	}
+
+	return schedule{
+		interval: interval,
+		align:    align,
+		offset:   offset,
+	}
+}
+
+// first returns the time of the first flush. Aligned schedules flush on the next wall clock
+// interval boundary, others one interval after now
+func (s schedule) first(now time.Time) time.Time {
+	if s.align {
+		return now.Truncate(s.interval).Add(s.interval + s.offset)
+	}
+	return now.Add(s.interval + s.offset)
+}
+
+// next returns the time of the flush following the one at t
+func (s schedule) next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// timestamp returns the timestamp of the datums flushed at t: the interval boundary for aligned
+// schedules, and the zero time otherwise to keep the build time
+func (s schedule) timestamp(t time.Time) time.Time {
+	if !s.align {
+		return time.Time{}
+	}
+	return t.Add(-s.offset).Truncate(s.interval)
+}
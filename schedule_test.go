@@ -0,0 +1,110 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	rnd := rand.New(rand.NewSource(1))
+
+	t.Run("OK - Default", func(t *testing.T) {
+		s := newSchedule(time.Minute, false, 0, rnd)
+
+		first := s.first(now)
+		assert.Equal(t, now.Add(time.Minute), first)
+		assert.Equal(t, first.Add(time.Minute), s.next(first))
+		assert.True(t, s.timestamp(first).IsZero())
+	})
+
+	t.Run("OK - Aligned", func(t *testing.T) {
+		s := newSchedule(time.Minute, true, 0, rnd)
+
+		first := s.first(now)
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 5, 0, 0, time.UTC), first)
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 6, 0, 0, time.UTC), s.next(first))
+		assert.Equal(t, first, s.timestamp(first.Add(50*time.Millisecond)))
+	})
+
+	t.Run("OK - Aligned with jitter", func(t *testing.T) {
+		s := newSchedule(time.Minute, true, 10*time.Second, rnd)
+		require.True(t, s.offset >= 0 && s.offset < 10*time.Second)
+
+		first := s.first(now)
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 5, 0, 0, time.UTC).Add(s.offset), first)
+		assert.Equal(t, first.Add(time.Minute), s.next(first))
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 5, 0, 0, time.UTC), s.timestamp(first))
+	})
+
+	t.Run("OK - Jitter is capped to the interval", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			s := newSchedule(time.Second, false, time.Hour, rnd)
+			assert.True(t, s.offset < time.Second)
+			assert.Equal(t, now.Add(time.Second+s.offset), s.first(now))
+		}
+	})
+}
+
+func TestPublisher__AlignedInterval(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	interval := 20 * time.Millisecond
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("counter", metrics.NewCounter()))
+
+	mu := sync.Mutex{}
+	timestamps := []time.Time{}
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range input.MetricData {
+			timestamps = append(timestamps, *d.Timestamp)
+		}
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*interval)
+	defer cancel()
+
+	p := NewPublisher(registry, "nmsp",
+		WithClient(cw),
+		WithContext(ctx),
+		WithInterval(interval),
+		WithAlignedInterval(),
+	)
+	p.Publish()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, timestamps)
+	for _, ts := range timestamps {
+		assert.Equal(t, ts.Truncate(interval), ts)
+	}
+}
@@ -0,0 +1,79 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"time"
+
+	"github.com/weareyolo/go-metrics"
+)
+
+// SelfMetricsPrefix is the reserved prefix of the metrics the publisher keeps about itself
+const SelfMetricsPrefix = "cloudmetrics."
+
+// selfMetrics instruments the publisher
+type selfMetrics struct {
+	registry      metrics.Registry
+	batchesSent   metrics.Counter
+	batchesFailed metrics.Counter
+	datumsSent    metrics.Counter
+	datumsDropped metrics.Counter
+	suppressed    metrics.Counter
+	rejected      metrics.Counter
+	quarantined   metrics.Counter
+	breakerState  metrics.Gauge
+	breakerOpened metrics.Counter
+	breakerDenied metrics.Counter
+	putLatency    metrics.Timer
+	lastSuccess   metrics.Gauge
+	pollDuration  metrics.Timer
+}
+
+func newSelfMetrics(r metrics.Registry) *selfMetrics {
+	if r == nil {
+		r = metrics.NewRegistry()
+	}
+
+	return &selfMetrics{
+		registry:      r,
+		batchesSent:   metrics.GetOrRegisterCounter(SelfMetricsPrefix+"batches.sent", r),
+		batchesFailed: metrics.GetOrRegisterCounter(SelfMetricsPrefix+"batches.failed", r),
+		datumsSent:    metrics.GetOrRegisterCounter(SelfMetricsPrefix+"datums.sent", r),
+		datumsDropped: metrics.GetOrRegisterCounter(SelfMetricsPrefix+"datums.dropped", r),
+		suppressed:    metrics.GetOrRegisterCounter(SelfMetricsPrefix+"datums.suppressed", r),
+		rejected:      metrics.GetOrRegisterCounter(SelfMetricsPrefix+"datums.rejected", r),
+		quarantined:   metrics.GetOrRegisterCounter(SelfMetricsPrefix+"datums.quarantined", r),
+		breakerState:  metrics.GetOrRegisterGauge(SelfMetricsPrefix+"breaker.state", r),
+		breakerOpened: metrics.GetOrRegisterCounter(SelfMetricsPrefix+"breaker.opened", r),
+		breakerDenied: metrics.GetOrRegisterCounter(SelfMetricsPrefix+"breaker.denied", r),
+		putLatency:    metrics.GetOrRegisterTimer(SelfMetricsPrefix+"put.latency", r),
+		lastSuccess:   metrics.GetOrRegisterGauge(SelfMetricsPrefix+"last_success", r),
+		pollDuration:  metrics.GetOrRegisterTimer(SelfMetricsPrefix+"poll.duration", r),
+	}
+}
+
+func (m *selfMetrics) batchSent(size int, t time.Time) {
+	m.batchesSent.Inc(1)
+	m.datumsSent.Inc(int64(size))
+	m.lastSuccess.Update(t.Unix())
+}
+
+func (m *selfMetrics) batchFailed() {
+	m.batchesFailed.Inc(1)
+}
+
+func (m *selfMetrics) dropped(size int) {
+	m.datumsDropped.Inc(int64(size))
+}
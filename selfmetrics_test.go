@@ -0,0 +1,101 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestPublisher__SelfMetrics(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	data := []*cloudwatch.MetricDatum{
+		{MetricName: aws.String("a"), Value: aws.Float64(1)},
+		{MetricName: aws.String("b"), Value: aws.Float64(2)},
+	}
+
+	t.Run("OK - Batches sent and failed", func(t *testing.T) {
+		selfRegistry := metrics.NewRegistry()
+		logger, _ := test.NewNullLogger()
+
+		cw := mock.NewCloudWatchMock(mc)
+		p := NewPublisher(metrics.NewRegistry(), "nmsp",
+			WithClient(cw),
+			WithLogger(logger),
+			WithSelfMetrics(selfRegistry),
+		).(*publisher)
+
+		cw.PutMetricDataMock.Return(nil, nil)
+		p.publishMetrics(namespacedData{"nmsp": data})
+
+		cw.PutMetricDataMock.Return(nil, errors.New("something happened"))
+		p.publishMetrics(namespacedData{"nmsp": data[:1]})
+
+		counter := func(name string) int64 {
+			return selfRegistry.Get(SelfMetricsPrefix + name).(metrics.Counter).Count()
+		}
+		assert.Equal(t, int64(1), counter("batches.sent"))
+		assert.Equal(t, int64(1), counter("batches.failed"))
+		assert.Equal(t, int64(2), counter("datums.sent"))
+		assert.Equal(t, int64(1), counter("datums.dropped"))
+		assert.Equal(t, int64(2), selfRegistry.Get(SelfMetricsPrefix+"put.latency").(metrics.Timer).Count())
+		assert.NotZero(t, selfRegistry.Get(SelfMetricsPrefix+"last_success").(metrics.Gauge).Value())
+	})
+
+	t.Run("OK - Self metrics published", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		require.NoError(t, registry.Register("app", metrics.NewCounter()))
+
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithSelfMetricsPublished(),
+		).(*publisher)
+		p.pollOnce(time.Time{})
+
+		names := []string{}
+		for _, d := range flatten(p.pollOnce(time.Time{})) {
+			names = append(names, *d.MetricName)
+		}
+		assert.Contains(t, names, "app")
+		assert.Contains(t, names, SelfMetricsPrefix+"batches.sent")
+		assert.Contains(t, names, SelfMetricsPrefix+"poll.duration.count")
+	})
+
+	t.Run("OK - Self metrics not published by default", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		require.NoError(t, registry.Register("app", metrics.NewCounter()))
+
+		p := NewPublisher(registry, "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+		).(*publisher)
+
+		for _, d := range flatten(p.pollOnce(time.Time{})) {
+			assert.False(t, strings.HasPrefix(*d.MetricName, SelfMetricsPrefix))
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/cloudmetrics/datum"
+	"github.com/weareyolo/go-metrics"
+)
+
+// Source is a registry published to its own namespace. Prefix is prepended to the names of its
+// metrics, after the name transformation, and Dimensions are added to its datums, replacing the
+// dimensions given to WithDimensions with the same name
+type Source struct {
+	Registry   metrics.Registry
+	Namespace  string
+	Prefix     string
+	Dimensions map[string]string
+}
+
+type source struct {
+	Source
+	dimensions []*cloudwatch.Dimension
+	sampler    *sampler
+}
+
+func newSource(s Source, rules []datum.ResolutionRule) *source {
+	keys := make([]string, 0, len(s.Dimensions))
+	for k := range s.Dimensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	dims := make([]*cloudwatch.Dimension, 0, len(keys))
+	for _, k := range keys {
+		dims = append(dims, &cloudwatch.Dimension{
+			Name:  aws.String(k),
+			Value: aws.String(s.Dimensions[k]),
+		})
+	}
+
+	return &source{
+		Source:     s,
+		dimensions: dims,
+		sampler:    newSampler(rules),
+	}
+}
+
+// decorate applies the source prefix and dimensions to the datums built from its registry, and
+// stamps them with t unless it is zero. Source dimensions replace the built ones of the same name
+func (s *source) decorate(data []*cloudwatch.MetricDatum, t time.Time) []*cloudwatch.MetricDatum {
+	if s.Prefix == "" && len(s.dimensions) == 0 && t.IsZero() {
+		return data
+	}
+
+	res := make([]*cloudwatch.MetricDatum, 0, len(data))
+	for _, d := range data {
+		c := *d
+		if s.Prefix != "" {
+			c.MetricName = aws.String(s.Prefix + aws.StringValue(d.MetricName))
+		}
+		if len(s.dimensions) > 0 {
+			dims := make([]*cloudwatch.Dimension, 0, len(d.Dimensions)+len(s.dimensions))
+			for _, dim := range d.Dimensions {
+				if _, ok := s.Dimensions[aws.StringValue(dim.Name)]; !ok {
+					dims = append(dims, dim)
+				}
+			}
+			c.Dimensions = append(dims, s.dimensions...)
+		}
+		if !t.IsZero() {
+			c.Timestamp = aws.Time(t.UTC())
+		}
+		res = append(res, &c)
+	}
+	return res
+}
+
+// namespacedData holds the datums to publish by namespace, since a PutMetricDataInput only
+// carries one namespace
+type namespacedData map[string][]*cloudwatch.MetricDatum
+
+func (d namespacedData) add(namespace string, data ...*cloudwatch.MetricDatum) {
+	if len(data) > 0 {
+		d[namespace] = append(d[namespace], data...)
+	}
+}
+
+func (d namespacedData) len() int {
+	n := 0
+	for _, data := range d {
+		n += len(data)
+	}
+	return n
+}
+
+// namespaces returns the namespaces in a stable order
+func (d namespacedData) namespaces() []string {
+	res := make([]string, 0, len(d))
+	for ns := range d {
+		res = append(res, ns)
+	}
+	sort.Strings(res)
+	return res
+}
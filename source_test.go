@@ -0,0 +1,172 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestSource__Decorate(t *testing.T) {
+	shared := []*cloudwatch.Dimension{{Name: aws.String("env"), Value: aws.String("prod")}}
+	data := []*cloudwatch.MetricDatum{{MetricName: aws.String("requests"), Dimensions: shared}}
+
+	t.Run("OK - Nothing to apply", func(t *testing.T) {
+		src := newSource(Source{Namespace: "nmsp"}, nil)
+		assert.Equal(t, data, src.decorate(data, time.Time{}))
+	})
+
+	t.Run("OK - Prefix and dimensions", func(t *testing.T) {
+		src := newSource(Source{
+			Namespace:  "nmsp",
+			Prefix:     "lib.",
+			Dimensions: map[string]string{"lib": "db", "component": "pool"},
+		}, nil)
+
+		res := src.decorate(data, time.Time{})
+		require.Len(t, res, 1)
+		assert.Equal(t, &cloudwatch.MetricDatum{
+			MetricName: aws.String("lib.requests"),
+			Dimensions: []*cloudwatch.Dimension{
+				{Name: aws.String("env"), Value: aws.String("prod")},
+				{Name: aws.String("component"), Value: aws.String("pool")},
+				{Name: aws.String("lib"), Value: aws.String("db")},
+			},
+		}, res[0])
+
+		// The built datums and their shared dimensions are left untouched
+		assert.Equal(t, "requests", *data[0].MetricName)
+		assert.Len(t, shared, 1)
+		assert.Len(t, data[0].Dimensions, 1)
+	})
+
+	t.Run("OK - Source dimensions replace built ones", func(t *testing.T) {
+		src := newSource(Source{
+			Namespace:  "nmsp",
+			Dimensions: map[string]string{"env": "staging", "lib": "db"},
+		}, nil)
+
+		res := src.decorate(data, time.Time{})
+		require.Len(t, res, 1)
+		assert.Equal(t, []*cloudwatch.Dimension{
+			{Name: aws.String("env"), Value: aws.String("staging")},
+			{Name: aws.String("lib"), Value: aws.String("db")},
+		}, res[0].Dimensions)
+		assert.Equal(t, "prod", *shared[0].Value)
+	})
+
+	t.Run("OK - Timestamp", func(t *testing.T) {
+		src := newSource(Source{Namespace: "nmsp"}, nil)
+		ts := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		res := src.decorate(data, ts)
+		require.Len(t, res, 1)
+		assert.Equal(t, ts, *res[0].Timestamp)
+		assert.Nil(t, data[0].Timestamp)
+	})
+}
+
+func TestPublisher__Sources(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	main := metrics.NewRegistry()
+	require.NoError(t, main.Register("app", metrics.NewCounter()))
+	db := metrics.NewRegistry()
+	require.NoError(t, db.Register("queries", metrics.NewCounter()))
+	cache := metrics.NewRegistry()
+	require.NoError(t, cache.Register("hits", metrics.NewCounter()))
+
+	mu := sync.Mutex{}
+	inputs := map[string][]string{}
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range input.MetricData {
+			inputs[*input.Namespace] = append(inputs[*input.Namespace], *d.MetricName)
+		}
+		return nil, nil
+	})
+
+	logger, _ := test.NewNullLogger()
+	p := NewPublisher(main, "service",
+		WithClient(cw),
+		WithLogger(logger),
+		WithSelfMetricsPublished(),
+		WithSources(
+			Source{Registry: db, Namespace: "libs", Prefix: "db.", Dimensions: map[string]string{"lib": "db"}},
+			Source{Registry: cache, Namespace: "libs", Prefix: "cache."},
+		),
+	).(*publisher)
+	require.Len(t, p.sources, 4)
+
+	data := p.pollOnce(time.Time{})
+	assert.Equal(t, []string{"libs", "service"}, data.namespaces())
+	require.Len(t, data["libs"], 2)
+	assert.ElementsMatch(t, []string{"db.queries", "cache.hits"},
+		[]string{*data["libs"][0].MetricName, *data["libs"][1].MetricName})
+	for _, d := range data["libs"] {
+		if *d.MetricName == "db.queries" {
+			assert.Equal(t, []*cloudwatch.Dimension{{Name: aws.String("lib"), Value: aws.String("db")}}, d.Dimensions)
+		} else {
+			assert.Empty(t, d.Dimensions)
+		}
+	}
+
+	res := p.publishMetrics(data)
+	assert.Equal(t, []string{"libs", "service"}, res.Namespaces)
+	assert.ElementsMatch(t, []string{"db.queries", "cache.hits"}, inputs["libs"])
+	assert.Contains(t, inputs["service"], "app")
+	assert.Contains(t, inputs["service"], SelfMetricsPrefix+"batches.sent")
+}
+
+func TestPublisher__SourcesOnly(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	db := metrics.NewRegistry()
+	require.NoError(t, db.Register("queries", metrics.NewCounter()))
+
+	p := NewPublisher(nil, "",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithSelfMetricsPublished(),
+		WithSources(Source{Registry: db, Namespace: "libs"}),
+	).(*publisher)
+	require.Len(t, p.sources, 2)
+	assert.Equal(t, "libs", p.sources[1].Namespace)
+	assert.Equal(t, []string{"libs"}, p.pollOnce(time.Time{}).namespaces())
+}
+
+func TestNew__InvalidSource(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	_, err := New(metrics.NewRegistry(), "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithSources(Source{Registry: metrics.NewRegistry()}),
+	)
+	require.EqualError(t, err, "sources require a registry and a namespace")
+}
@@ -0,0 +1,63 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"sync"
+	"time"
+
+	"github.com/weareyolo/cloudmetrics/health"
+)
+
+// deliveryStatus tracks the health of the metric delivery. It is updated by the publishing loop
+// and read concurrently through Publisher.Status
+type deliveryStatus struct {
+	mu     sync.Mutex
+	status health.Status
+}
+
+func (s *deliveryStatus) attempted(t time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.LastAttempt = t
+	if err != nil {
+		s.status.ConsecutiveFailures++
+		s.status.LastError = err
+		return
+	}
+
+	s.status.LastSuccess = t
+	s.status.ConsecutiveFailures = 0
+}
+
+func (s *deliveryStatus) queued(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.QueueDepth = n
+}
+
+func (s *deliveryStatus) get() health.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status
+}
+
+// Status reports the health of the metric delivery
+func (p *publisher) Status() health.Status {
+	return p.status.get()
+}
@@ -0,0 +1,68 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestPublisher__Status(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	logger, _ := test.NewNullLogger()
+	cw := mock.NewCloudWatchMock(mc)
+	p := NewPublisher(metrics.NewRegistry(), "nmsp",
+		WithClient(cw),
+		WithLogger(logger),
+		WithInterval(time.Second),
+	).(*publisher)
+
+	data := []*cloudwatch.MetricDatum{{MetricName: aws.String("a"), Value: aws.Float64(1)}}
+
+	s := p.Status()
+	assert.Equal(t, time.Second, s.Interval)
+	assert.True(t, s.LastAttempt.IsZero())
+	assert.True(t, s.LastSuccess.IsZero())
+
+	cw.PutMetricDataMock.Return(nil, errors.New("something happened"))
+	p.publishMetrics(namespacedData{"nmsp": data})
+	p.publishMetrics(namespacedData{"nmsp": data})
+
+	s = p.Status()
+	assert.False(t, s.LastAttempt.IsZero())
+	assert.True(t, s.LastSuccess.IsZero())
+	assert.Equal(t, 2, s.ConsecutiveFailures)
+	assert.EqualError(t, s.LastError, "something happened")
+	assert.Zero(t, s.QueueDepth)
+
+	cw.PutMetricDataMock.Return(nil, nil)
+	p.publishMetrics(namespacedData{"nmsp": data})
+
+	s = p.Status()
+	assert.Equal(t, s.LastAttempt, s.LastSuccess)
+	assert.Zero(t, s.ConsecutiveFailures)
+	assert.EqualError(t, s.LastError, "something happened")
+}
@@ -0,0 +1,192 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/go-metrics"
+)
+
+// MetricType identifies a built-in metric type, for settings applied per type
+type MetricType int
+
+const (
+	// TypeOther is any metric not handled by the DatumBuilder
+	TypeOther MetricType = iota
+	// TypeCounter is metrics.Counter
+	TypeCounter
+	// TypeGauge is metrics.Gauge
+	TypeGauge
+	// TypeGaugeFloat64 is metrics.GaugeFloat64
+	TypeGaugeFloat64
+	// TypeHistogram is metrics.Histogram
+	TypeHistogram
+	// TypeMeter is metrics.Meter
+	TypeMeter
+	// TypeTimer is metrics.Timer
+	TypeTimer
+	// TypeHealthcheck is metrics.Healthcheck
+	TypeHealthcheck
+	// TypeEWMA is metrics.EWMA
+	TypeEWMA
+)
+
+// metricType returns the type of i, checked in the same order as the DatumBuilder dispatch
+func metricType(i interface{}) MetricType {
+	switch i.(type) {
+	case metrics.Counter:
+		return TypeCounter
+	case metrics.Gauge:
+		return TypeGauge
+	case metrics.GaugeFloat64:
+		return TypeGaugeFloat64
+	case metrics.Histogram:
+		return TypeHistogram
+	case metrics.Meter:
+		return TypeMeter
+	case metrics.Timer:
+		return TypeTimer
+	case metrics.Healthcheck:
+		return TypeHealthcheck
+	case metrics.EWMA:
+		return TypeEWMA
+	default:
+		return TypeOther
+	}
+}
+
+// Suppression decides which datums of a metric type are not published.
+// SkipZero skips metrics whose datums are all zero, SkipUnchanged skips metrics whose datums
+// did not change since they were last published. Heartbeat forces a publish after Heartbeat-1
+// skipped intervals in a row so that alarms do not see missing data, 0 disables it.
+type Suppression struct {
+	SkipZero      bool
+	SkipUnchanged bool
+	Heartbeat     int
+}
+
+// metricKey identifies a metric across intervals
+type metricKey struct {
+	src  *source
+	name string
+}
+
+// suppressionState is what is known of a metric since it was last published
+type suppressionState struct {
+	values  []float64
+	skipped int
+	poll    uint64
+}
+
+// suppressor keeps the state of the metrics across intervals to apply the Suppression policies
+type suppressor struct {
+	policies map[MetricType]Suppression
+	state    map[metricKey]*suppressionState
+	poll     uint64
+}
+
+func newSuppressor(policies map[MetricType]Suppression) *suppressor {
+	return &suppressor{
+		policies: policies,
+		state:    map[metricKey]*suppressionState{},
+	}
+}
+
+// begin starts a new interval
+func (s *suppressor) begin() {
+	s.poll++
+}
+
+// skip reports whether the datums built for a metric should not be published this interval
+func (s *suppressor) skip(src *source, name string, typ MetricType, data []*cloudwatch.MetricDatum) bool {
+	key := metricKey{src: src, name: name}
+	st, ok := s.state[key]
+	if ok {
+		st.poll = s.poll
+	}
+
+	policy, hasPolicy := s.policies[typ]
+	if !hasPolicy || len(data) == 0 {
+		return false
+	}
+	if !ok {
+		st = &suppressionState{poll: s.poll}
+		s.state[key] = st
+	}
+
+	values := datumValues(data)
+	skip := (policy.SkipZero && allZero(values)) ||
+		(policy.SkipUnchanged && st.values != nil && equalValues(st.values, values))
+	if skip && (policy.Heartbeat <= 0 || st.skipped+1 < policy.Heartbeat) {
+		st.skipped++
+		return true
+	}
+
+	st.values = values
+	st.skipped = 0
+	return false
+}
+
+// sweep forgets the metrics which were not built during the interval, such as the ones
+// unregistered or expired
+func (s *suppressor) sweep() {
+	for key, st := range s.state {
+		if st.poll != s.poll {
+			delete(s.state, key)
+		}
+	}
+}
+
+// datumValues flattens the values carried by data
+func datumValues(data []*cloudwatch.MetricDatum) []float64 {
+	res := make([]float64, 0, len(data))
+	for _, d := range data {
+		res = append(res, aws.Float64Value(d.Value))
+		if sv := d.StatisticValues; sv != nil {
+			res = append(res,
+				aws.Float64Value(sv.SampleCount),
+				aws.Float64Value(sv.Sum),
+				aws.Float64Value(sv.Minimum),
+				aws.Float64Value(sv.Maximum),
+			)
+		}
+		for _, v := range d.Values {
+			res = append(res, aws.Float64Value(v))
+		}
+	}
+	return res
+}
+
+func allZero(values []float64) bool {
+	for _, v := range values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func equalValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
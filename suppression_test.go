@@ -0,0 +1,111 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestSuppressor__Skip(t *testing.T) {
+	src := newSource(Source{Namespace: "nmsp"}, nil)
+	datums := func(v float64) []*cloudwatch.MetricDatum {
+		return []*cloudwatch.MetricDatum{{MetricName: aws.String("m"), Value: aws.Float64(v)}}
+	}
+
+	t.Run("OK - No policy for the type", func(t *testing.T) {
+		s := newSuppressor(map[MetricType]Suppression{TypeGauge: {SkipZero: true}})
+		assert.False(t, s.skip(src, "m", TypeCounter, datums(0)))
+	})
+
+	t.Run("OK - Skip zero", func(t *testing.T) {
+		s := newSuppressor(map[MetricType]Suppression{TypeCounter: {SkipZero: true}})
+		assert.True(t, s.skip(src, "m", TypeCounter, datums(0)))
+		assert.False(t, s.skip(src, "m", TypeCounter, datums(1)))
+		assert.False(t, s.skip(src, "m", TypeCounter, datums(1)))
+	})
+
+	t.Run("OK - Skip unchanged", func(t *testing.T) {
+		s := newSuppressor(map[MetricType]Suppression{TypeCounter: {SkipUnchanged: true}})
+		assert.False(t, s.skip(src, "m", TypeCounter, datums(0)))
+		assert.True(t, s.skip(src, "m", TypeCounter, datums(0)))
+		assert.False(t, s.skip(src, "m", TypeCounter, datums(2)))
+		assert.True(t, s.skip(src, "m", TypeCounter, datums(2)))
+
+		// Metrics are tracked by source
+		other := newSource(Source{Namespace: "other"}, nil)
+		assert.False(t, s.skip(other, "m", TypeCounter, datums(2)))
+	})
+
+	t.Run("OK - Heartbeat", func(t *testing.T) {
+		s := newSuppressor(map[MetricType]Suppression{TypeGauge: {SkipUnchanged: true, Heartbeat: 3}})
+		published := []bool{}
+		for i := 0; i < 7; i++ {
+			published = append(published, !s.skip(src, "m", TypeGauge, datums(5)))
+		}
+		assert.Equal(t, []bool{true, false, false, true, false, false, true}, published)
+	})
+}
+
+func TestPublisher__Suppression(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	idle := metrics.NewCounter()
+	busy := metrics.NewCounter()
+	gauge := metrics.NewGauge()
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("idle", idle))
+	require.NoError(t, registry.Register("busy", busy))
+	require.NoError(t, registry.Register("gauge", gauge))
+
+	selfRegistry := metrics.NewRegistry()
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithSelfMetrics(selfRegistry),
+		WithSuppression(TypeCounter, Suppression{SkipZero: true, SkipUnchanged: true}),
+	).(*publisher)
+
+	names := func() []string {
+		res := []string{}
+		for _, d := range flatten(p.pollOnce(time.Time{})) {
+			res = append(res, *d.MetricName)
+		}
+		return res
+	}
+
+	busy.Inc(1)
+	assert.ElementsMatch(t, []string{"busy", "gauge"}, names())
+	assert.ElementsMatch(t, []string{"gauge"}, names())
+
+	busy.Inc(1)
+	assert.ElementsMatch(t, []string{"busy", "gauge"}, names())
+	assert.Equal(t, int64(4), selfRegistry.Get(SelfMetricsPrefix+"datums.suppressed").(metrics.Counter).Count())
+	assert.Len(t, p.suppressor.state, 2)
+
+	// The state of unregistered metrics is forgotten on the next poll
+	registry.Unregister("idle")
+	names()
+	assert.Len(t, p.suppressor.state, 1)
+	assert.Contains(t, p.suppressor.state, metricKey{src: p.sources[0], name: "busy"})
+}
@@ -0,0 +1,68 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/go-metrics"
+)
+
+// untimedBuilder adapts a DatumBuilder unaware of timestamps. The datums it builds are stamped
+// with the snapshot timestamp afterwards, see source.decorate
+type untimedBuilder struct {
+	DatumBuilder
+}
+
+// timestamped returns b as a TimestampedDatumBuilder
+func timestamped(b DatumBuilder) TimestampedDatumBuilder {
+	if tb, ok := b.(TimestampedDatumBuilder); ok {
+		return tb
+	}
+	return untimedBuilder{b}
+}
+
+func (b untimedBuilder) BuildCounterDataAt(v metrics.Counter, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildCounterData(v, name)
+}
+
+func (b untimedBuilder) BuildGaugeDataAt(v metrics.Gauge, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildGaugeData(v, name)
+}
+
+func (b untimedBuilder) BuildGaugeFloat64DataAt(v metrics.GaugeFloat64, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildGaugeFloat64Data(v, name)
+}
+
+func (b untimedBuilder) BuildMeterDataAt(v metrics.Meter, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildMeterData(v, name)
+}
+
+func (b untimedBuilder) BuildHistogramDataAt(v metrics.Histogram, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildHistogramData(v, name)
+}
+
+func (b untimedBuilder) BuildTimerDataAt(v metrics.Timer, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildTimerData(v, name)
+}
+
+func (b untimedBuilder) BuildHealthcheckDataAt(v metrics.Healthcheck, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildHealthcheckData(v, name)
+}
+
+func (b untimedBuilder) BuildEWMADataAt(v metrics.EWMA, name string, _ time.Time) []*cloudwatch.MetricDatum {
+	return b.BuildEWMAData(v, name)
+}
@@ -0,0 +1,74 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// DatumProvider is implemented by metrics generating their own datums
+type DatumProvider interface {
+	Datums(name string) []*cloudwatch.MetricDatum
+}
+
+// BuildFunc generates the datums of a metric type unknown to the DatumBuilder
+type BuildFunc func(metric interface{}, name string) []*cloudwatch.MetricDatum
+
+type typeBuilder struct {
+	typ   reflect.Type
+	build BuildFunc
+}
+
+// typeBuilders dispatches metrics to the BuildFunc registered for their type. Concrete types
+// are looked up first, then interfaces in registration order
+type typeBuilders struct {
+	concrete   map[reflect.Type]BuildFunc
+	interfaces []typeBuilder
+}
+
+func newTypeBuilders(builders []typeBuilder) *typeBuilders {
+	tb := &typeBuilders{concrete: map[reflect.Type]BuildFunc{}}
+	for _, b := range builders {
+		if b.typ.Kind() == reflect.Interface {
+			tb.interfaces = append(tb.interfaces, b)
+			continue
+		}
+		if _, ok := tb.concrete[b.typ]; !ok {
+			tb.concrete[b.typ] = b.build
+		}
+	}
+	return tb
+}
+
+// find returns the BuildFunc registered for the type of i
+func (tb *typeBuilders) find(i interface{}) (BuildFunc, bool) {
+	t := reflect.TypeOf(i)
+	if t == nil {
+		return nil, false
+	}
+
+	if f, ok := tb.concrete[t]; ok {
+		return f, true
+	}
+
+	for _, b := range tb.interfaces {
+		if t.Implements(b.typ) {
+			return b.build, true
+		}
+	}
+	return nil, false
+}
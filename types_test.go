@@ -0,0 +1,109 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+type sketch struct{ value float64 }
+
+func (s *sketch) Quantile(float64) float64 { return s.value }
+
+type quantiler interface {
+	Quantile(q float64) float64
+}
+
+type otherSketch struct{}
+
+func (otherSketch) Quantile(float64) float64 { return 2 }
+
+type provider struct{}
+
+func (provider) Datums(name string) []*cloudwatch.MetricDatum {
+	return []*cloudwatch.MetricDatum{{MetricName: aws.String(name + ".provided")}}
+}
+
+func TestPublisher__TypeBuilders(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	counter := metrics.NewCounter()
+	registry := &staticRegistry{
+		Registry: metrics.NewRegistry(),
+		metrics: map[string]interface{}{
+			"sketch":   &sketch{value: 1},
+			"other":    otherSketch{},
+			"provider": provider{},
+			"counter":  counter,
+		},
+	}
+
+	buildWith := func(suffix string) BuildFunc {
+		return func(metric interface{}, name string) []*cloudwatch.MetricDatum {
+			return []*cloudwatch.MetricDatum{{
+				MetricName: aws.String(name + suffix),
+				Value:      aws.Float64(metric.(quantiler).Quantile(.5)),
+			}}
+		}
+	}
+
+	logger, hook := test.NewNullLogger()
+	p := NewPublisher(registry, "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithBuilder(mock.NewDatumBuilderMock(mc)),
+		WithLogger(logger),
+		WithTypeBuilder(reflect.TypeOf((*quantiler)(nil)).Elem(), buildWith(".interface")),
+		WithTypeBuilder(reflect.TypeOf((*sketch)(nil)), buildWith(".concrete")),
+		WithTypeBuilder(reflect.TypeOf((*metrics.Counter)(nil)).Elem(), func(metric interface{}, name string) []*cloudwatch.MetricDatum {
+			return []*cloudwatch.MetricDatum{{MetricName: aws.String(name + ".custom")}}
+		}),
+	).(*publisher)
+
+	names := map[string]float64{}
+	for _, d := range flatten(p.pollOnce(time.Time{})) {
+		names[*d.MetricName] = aws.Float64Value(d.Value)
+	}
+
+	assert.Equal(t, map[string]float64{
+		"sketch.concrete":   1,
+		"other.interface":   2,
+		"provider.provided": 0,
+		"counter.custom":    0,
+	}, names)
+	assert.Empty(t, hook.Entries)
+}
+
+func TestNew__InvalidTypeBuilder(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	_, err := New(metrics.NewRegistry(), "nmsp",
+		WithClient(mock.NewCloudWatchMock(mc)),
+		WithTypeBuilder(nil, nil),
+	)
+	require.EqualError(t, err, "type builders require a type and a function")
+}
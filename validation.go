@@ -0,0 +1,51 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/weareyolo/cloudmetrics/datum"
+)
+
+// sanitize applies the invalid datum policy to the datums CloudWatch would reject, so that a
+// single bad datum does not fail its whole batch
+func (p *publisher) sanitize(data namespacedData) namespacedData {
+	for namespace, datums := range data {
+		valid := datums[:0]
+		for _, d := range datums {
+			name := aws.StringValue(d.MetricName)
+			err := datum.Validate(d)
+			if err != nil && p.onInvalid == datum.ClampInvalid {
+				p.logger.WithField("metric", name).WithError(err).Warnf("clamping invalid datum")
+				err = datum.Clamp(d)
+			}
+			if err != nil {
+				p.logger.WithField("metric", name).WithError(err).Warnf("dropping invalid datum")
+				p.self.rejected.Inc(1)
+				p.deadLetter(namespace, []*cloudwatch.MetricDatum{d}, err, 0)
+				continue
+			}
+			valid = append(valid, d)
+		}
+
+		if len(valid) == 0 {
+			delete(data, namespace)
+			continue
+		}
+		data[namespace] = valid
+	}
+	return data
+}
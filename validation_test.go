@@ -0,0 +1,113 @@
+package cloudmetrics
+
+//	Copyright 2020 @weareyolo
+//
+//	Licensed under the Apache License, Version 2.0 (the "License");
+//	you may not use this file except in compliance with the License.
+//	You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+//	Unless required by applicable law or agreed to in writing, software
+//	distributed under the License is distributed on an "AS IS" BASIS,
+//	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	See the License for the specific language governing permissions and
+//	limitations under the License
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gojuno/minimock/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weareyolo/cloudmetrics/datum"
+	"github.com/weareyolo/cloudmetrics/mock"
+	"github.com/weareyolo/go-metrics"
+)
+
+func TestPublisher__Sanitize(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	data := func() namespacedData {
+		return namespacedData{
+			"nmsp": {
+				{MetricName: aws.String("ok"), Value: aws.Float64(1)},
+				{MetricName: aws.String("nan"), Value: aws.Float64(math.NaN())},
+				{MetricName: aws.String("inf"), Value: aws.Float64(math.Inf(1))},
+			},
+			"other": {
+				{MetricName: aws.String("nan"), Value: aws.Float64(math.NaN())},
+			},
+		}
+	}
+
+	t.Run("OK - Drop", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		selfRegistry := metrics.NewRegistry()
+		p := NewPublisher(metrics.NewRegistry(), "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithLogger(logger),
+			WithSelfMetrics(selfRegistry),
+		).(*publisher)
+
+		res := p.sanitize(data())
+		assert.Equal(t, []string{"nmsp"}, res.namespaces())
+		require.Len(t, res["nmsp"], 1)
+		assert.Equal(t, "ok", *res["nmsp"][0].MetricName)
+		assert.Equal(t, int64(3), selfRegistry.Get(SelfMetricsPrefix+"datums.rejected").(metrics.Counter).Count())
+
+		require.Len(t, hook.Entries, 3)
+		for _, e := range hook.Entries {
+			assert.Equal(t, logrus.WarnLevel, e.Level)
+			assert.Equal(t, "dropping invalid datum", e.Message)
+		}
+		assert.Equal(t, "nan", hook.Entries[0].Data["metric"])
+	})
+
+	t.Run("OK - Clamp", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		selfRegistry := metrics.NewRegistry()
+		p := NewPublisher(metrics.NewRegistry(), "nmsp",
+			WithClient(mock.NewCloudWatchMock(mc)),
+			WithLogger(logger),
+			WithSelfMetrics(selfRegistry),
+			WithInvalidDatumPolicy(datum.ClampInvalid),
+		).(*publisher)
+
+		res := p.sanitize(data())
+		assert.Equal(t, []string{"nmsp"}, res.namespaces())
+		require.Len(t, res["nmsp"], 2)
+		assert.Equal(t, "inf", *res["nmsp"][1].MetricName)
+		assert.Equal(t, datum.MaxValue, *res["nmsp"][1].Value)
+		assert.Equal(t, int64(2), selfRegistry.Get(SelfMetricsPrefix+"datums.rejected").(metrics.Counter).Count())
+		assert.Len(t, hook.Entries, 5)
+	})
+}
+
+func TestPublisher__FlushInvalid(t *testing.T) {
+	mc := minimock.NewController(t)
+	defer mc.Finish()
+
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterGaugeFloat64("broken", registry).Update(math.NaN())
+	metrics.GetOrRegisterCounter("requests", registry).Inc(1)
+
+	logger, _ := test.NewNullLogger()
+	cw := mock.NewCloudWatchMock(mc)
+	cw.PutMetricDataMock.Set(func(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+		require.Len(t, input.MetricData, 1)
+		assert.Equal(t, "requests", *input.MetricData[0].MetricName)
+		return nil, nil
+	})
+
+	p := NewPublisher(registry, "nmsp", WithClient(cw), WithLogger(logger)).(*publisher)
+	p.flush(time.Time{})
+	assert.EqualValues(t, 1, cw.PutMetricDataAfterCounter())
+}